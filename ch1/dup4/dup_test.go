@@ -0,0 +1,157 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestExternalSort checks that the output is the same whether or not
+// the lines fit in memory, and agrees with a map of counts.
+func TestExternalSort(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dup4test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rng := rand.New(rand.NewSource(1))
+	counts := make(map[string]int)
+	var files []string
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+		for j := 0; j < 4000; j++ {
+			line := fmt.Sprintf("line %d", rng.Intn(5000))
+			counts[line]++
+			fmt.Fprintln(&buf, line)
+		}
+		name := filepath.Join(dir, fmt.Sprintf("in%d", i))
+		if err := ioutil.WriteFile(name, buf.Bytes(), 0666); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, name)
+	}
+	var want []string
+	for line, n := range counts {
+		if n > 1 {
+			want = append(want, fmt.Sprintf("%d\t%s", n, line))
+		}
+	}
+	sort.Strings(want)
+
+	defer func(mem, locs int, tmp string) {
+		*memLimit, *maxLocs, *tmpDir = mem, locs, tmp
+	}(*memLimit, *maxLocs, *tmpDir)
+	*maxLocs = 0
+	*tmpDir = dir
+	var outputs []string
+	for _, mem := range []int{1 << 30, 100000, 2000} { // in memory, one merge, several
+		*memLimit = mem
+		var out bytes.Buffer
+		if err := run(files, &out); err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, out.String())
+	}
+	if outputs[1] != outputs[0] || outputs[2] != outputs[0] {
+		t.Errorf("external sort changes the output")
+	}
+
+	var got []string
+	lines := strings.Split(strings.TrimSuffix(outputs[0], "\n"), "\n")
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		got = append(got, fields[0]+"\t"+fields[1])
+		var n int
+		fmt.Sscan(fields[0], &n)
+		if locs := strings.Fields(fields[2]); len(locs) != n {
+			t.Errorf("%q has %d locations, want %d", line, len(locs), n)
+		}
+	}
+	sort.Strings(got)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %d duplicates, want %d", len(got), len(want))
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != len(files) {
+		t.Errorf("temporary files remain in %s", dir)
+	}
+}
+
+func TestLocations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dup4test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	ioutil.WriteFile(a, []byte("x\ny\nx\r\nx\n"), 0666)
+	ioutil.WriteFile(b, []byte("z\nx\ny"), 0666)
+
+	defer func(locs int) { *maxLocs = locs }(*maxLocs)
+	*maxLocs = 3
+	var out bytes.Buffer
+	if err := run([]string{a, b}, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("4\tx\t%[1]s:1 %[1]s:3 %[1]s:4 ...\n2\ty\t%[1]s:2 %[2]s:3\n", a, b)
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", &out, want)
+	}
+}
+
+func TestMaxLine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dup4test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "long")
+	ioutil.WriteFile(name, []byte("short\n"+strings.Repeat("x", 100)+"\nshort\n"), 0666)
+
+	defer func(max int) { *maxLine = max }(*maxLine)
+	*maxLine = 50
+	err = run([]string{name}, ioutil.Discard)
+	if want := name + ":2: line longer than 50 bytes"; err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("run = %v, want error %s", err, want)
+	}
+
+	*maxLine = 200
+	var out bytes.Buffer
+	if err := run([]string{name}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("2\tshort\t%[1]s:1 %[1]s:3\n", name); out.String() != want {
+		t.Errorf("got %q, want %q", &out, want)
+	}
+}
+
+func TestNormalizer(t *testing.T) {
+	for _, test := range []struct {
+		norm       normalizer
+		line, want string
+	}{
+		{normalizer{}, "  A  b ", "  A  b "},
+		{normalizer{trim: true}, " \tA  b \n", "A  b"},
+		{normalizer{squeeze: true}, " \tA  b ", " A b "},
+		{normalizer{trim: true, squeeze: true, fold: true}, " \tA  B ", "a b"},
+		{normalizer{fold: true}, "ſtraße", "straße"},
+		{normalizer{field: regexp.MustCompile(`id=\d+`)}, "x id=42 y", "id=42"},
+		{normalizer{field: regexp.MustCompile(`user=(\w+)`), fold: true}, "GET user=Bob", "bob"},
+		{normalizer{field: regexp.MustCompile(`user=(\w+)`)}, "GET /", ""}, // ignored
+	} {
+		got, ok := test.norm.key(test.line)
+		if got != test.want || ok != (test.want != "") {
+			t.Errorf("%+v.key(%q) = %q, %t, want %q", test.norm, test.line, got, ok, test.want)
+		}
+	}
+}
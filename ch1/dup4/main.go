@@ -0,0 +1,209 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+// Dup4 prints the count and text of lines that appear more than once
+// in the input, followed by the file and line number of each
+// occurrence.  It reads from stdin or from a list of named files.
+//
+// Unlike dup1, dup2 and dup3, which keep every distinct line in
+// memory, dup4 uses a bounded amount of memory (-mem), so it can
+// process inputs larger than that: when the lines read so far exceed
+// the limit, they are sorted and written to a temporary file, and the
+// sorted files are merged at the end.  The lines are printed in
+// sorted order.  A line longer than -maxline is an error.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	memLimit = flag.Int("mem", 64<<20, "approximate memory limit, in bytes")
+	maxLine  = flag.Int("maxline", 1<<20, "maximum length of a line, in bytes; longer lines are an error")
+	maxLocs  = flag.Int("locs", 10, "maximum number of locations to print for each line (0 means all)")
+	trim     = flag.Bool("trim", false, "ignore leading and trailing white space")
+	fold     = flag.Bool("fold", false, "ignore case")
+	squeeze  = flag.Bool("squeeze", false, "treat each sequence of white space as a single space")
+	field    = flag.String("field", "", "compare only the part of each line matched by this regular expression,\n"+
+		"or by its first parenthesized group; lines that don't match are ignored")
+	tmpDir = flag.String("tmp", "", "directory for temporary files (default "+os.TempDir()+")")
+)
+
+func main() {
+	flag.Parse()
+	if err := run(flag.Args(), os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "dup4: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(files []string, out io.Writer) error {
+	if *maxLine < 1 {
+		return fmt.Errorf("-maxline must be positive")
+	}
+	norm := &normalizer{trim: *trim, fold: *fold, squeeze: *squeeze}
+	if *field != "" {
+		re, err := regexp.Compile(*field)
+		if err != nil {
+			return err
+		}
+		norm.field = re
+	}
+	dir, err := ioutil.TempDir(*tmpDir, "dup4")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	s := &sorter{dir: dir, limit: *memLimit}
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for i, name := range files {
+		if err := readLines(name, i, norm, s); err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(out)
+	var g group
+	err = s.each(func(r record) {
+		if g.n > 0 && r.key != g.key {
+			g.print(w, files)
+			g = group{}
+		}
+		g.add(r)
+	})
+	if err != nil {
+		return err
+	}
+	g.print(w, files)
+	return w.Flush()
+}
+
+// readLines adds a record for each line of the named file, the
+// standard input if name is "-", to s.
+func readLines(name string, file int, norm *normalizer, s *sorter) error {
+	f := os.Stdin
+	if name != "-" {
+		var err error
+		if f, err = os.Open(name); err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+	// The scanner's buffer grows only as far as -maxline, so that
+	// one long line cannot exhaust memory.
+	size := 4096
+	if size > *maxLine {
+		size = *maxLine
+	}
+	in := bufio.NewScanner(f)
+	in.Buffer(make([]byte, 0, size), *maxLine)
+	lineno := 0
+	for in.Scan() {
+		lineno++
+		if key, ok := norm.key(in.Text()); ok {
+			if err := s.add(record{key, file, lineno}); err != nil {
+				return err
+			}
+		}
+	}
+	if err := in.Err(); err == bufio.ErrTooLong {
+		return fmt.Errorf("%s:%d: line longer than %d bytes (see -maxline)", name, lineno+1, *maxLine)
+	} else if err != nil {
+		return fmt.Errorf("reading %s: %v", name, err)
+	}
+	return nil
+}
+
+// A group is the sequence of records with the same key.
+type group struct {
+	key  string
+	n    int
+	locs []record // the first -locs records
+}
+
+func (g *group) add(r record) {
+	g.key = r.key
+	g.n++
+	if *maxLocs == 0 || len(g.locs) < *maxLocs {
+		g.locs = append(g.locs, r)
+	}
+}
+
+// print prints the group, if it is a duplicate.
+func (g *group) print(w io.Writer, files []string) {
+	if g.n < 2 {
+		return
+	}
+	fmt.Fprintf(w, "%d\t%s\t", g.n, g.key)
+	for i, r := range g.locs {
+		if i > 0 {
+			fmt.Fprint(w, " ")
+		}
+		fmt.Fprintf(w, "%s:%d", files[r.file], r.line)
+	}
+	if g.n > len(g.locs) {
+		fmt.Fprint(w, " ...")
+	}
+	fmt.Fprintln(w)
+}
+
+// A normalizer maps each line to the key by which it is compared.
+type normalizer struct {
+	trim, fold, squeeze bool
+	field               *regexp.Regexp // if non-nil, selects the part to compare
+}
+
+// key returns the key of line, or false if the line is to be ignored.
+func (n *normalizer) key(line string) (string, bool) {
+	if n.field != nil {
+		m := n.field.FindStringSubmatch(line)
+		if m == nil {
+			return "", false
+		}
+		line = m[0]
+		if len(m) > 1 {
+			line = m[1]
+		}
+	}
+	if n.squeeze {
+		line = squeezeSpace(line)
+	}
+	if n.trim {
+		line = strings.TrimSpace(line)
+	}
+	if n.fold {
+		// Mapping to upper case first makes, for example,
+		// ſ (long s) equal to s.
+		line = strings.ToLower(strings.ToUpper(line))
+	}
+	return line, true
+}
+
+// squeezeSpace replaces each sequence of white space in s by a space.
+func squeezeSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,233 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// A record is an occurrence of a key at a line of a file.
+type record struct {
+	key  string
+	file int // index of the file name
+	line int
+}
+
+func less(x, y *record) bool {
+	if x.key != y.key {
+		return x.key < y.key
+	}
+	if x.file != y.file {
+		return x.file < y.file
+	}
+	return x.line < y.line
+}
+
+// recordSize is the approximate memory used by a record in a slice,
+// not counting its key, plus slack for the slice's spare capacity.
+const recordSize = 64
+
+// maxRuns is the maximum number of runs merged at once, which limits
+// the number of open files and their buffers.
+const maxRuns = 64
+
+// A sorter sorts records.  When their size exceeds a limit, it sorts
+// those it holds in memory and writes them to a file (a "run"); it
+// finally merges the runs.
+type sorter struct {
+	dir   string // for the runs
+	limit int    // of the size of recs, in bytes
+	size  int
+	recs  []record
+	runs  []string // the names of the runs
+}
+
+func (s *sorter) add(r record) error {
+	s.recs = append(s.recs, r)
+	s.size += len(r.key) + recordSize
+	if s.size > s.limit {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill writes the records in memory to a new run.
+func (s *sorter) spill() error {
+	sort.Slice(s.recs, func(i, j int) bool { return less(&s.recs[i], &s.recs[j]) })
+	err := s.newRun(func(w *runWriter) error {
+		for i := range s.recs {
+			if err := w.write(&s.recs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	s.recs, s.size = nil, 0 // release the keys
+	return err
+}
+
+// newRun creates a run containing the records written by fill,
+// which must write them in order.
+func (s *sorter) newRun(fill func(w *runWriter) error) error {
+	f, err := ioutil.TempFile(s.dir, "run")
+	if err != nil {
+		return err
+	}
+	w := &runWriter{w: bufio.NewWriter(f)}
+	err = fill(w)
+	if err == nil {
+		err = w.w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, f.Name())
+	return nil
+}
+
+// each calls f for each record in order.
+func (s *sorter) each(f func(record)) error {
+	if len(s.runs) == 0 {
+		// Everything fits in memory.
+		sort.Slice(s.recs, func(i, j int) bool { return less(&s.recs[i], &s.recs[j]) })
+		for _, r := range s.recs {
+			f(r)
+		}
+		return nil
+	}
+	if len(s.recs) > 0 {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	// Merge the runs, maxRuns at a time, until few enough remain.
+	for len(s.runs) > maxRuns {
+		runs := s.runs[:maxRuns]
+		s.runs = s.runs[maxRuns:]
+		var werr error
+		err := s.newRun(func(w *runWriter) error {
+			err := merge(runs, func(r record) {
+				if werr == nil {
+					werr = w.write(&r)
+				}
+			})
+			if err == nil {
+				err = werr
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return merge(s.runs, f)
+}
+
+// merge calls f for each record of the named runs, in order, and
+// removes the runs.
+func merge(runs []string, f func(record)) error {
+	var h runHeap
+	for _, name := range runs {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(name)
+		defer file.Close()
+		r := &runReader{r: bufio.NewReader(file), name: name}
+		if ok, err := r.next(); err != nil {
+			return err
+		} else if ok {
+			h = append(h, r)
+		}
+	}
+	heap.Init(&h)
+	for len(h) > 0 {
+		r := h[0]
+		f(r.rec)
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return nil
+}
+
+// A runHeap is a heap of runs, ordered by their current records.
+type runHeap []*runReader
+
+func (h runHeap) Len() int            { return len(h) }
+func (h runHeap) Less(i, j int) bool  { return less(&h[i].rec, &h[j].rec) }
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*runReader)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// In a run, each record is encoded as the length of the key, the key,
+// the file index and the line number, the numbers as uvarints.
+
+type runWriter struct {
+	w   *bufio.Writer
+	buf [3 * binary.MaxVarintLen64]byte
+}
+
+func (w *runWriter) write(r *record) error {
+	n := binary.PutUvarint(w.buf[:], uint64(len(r.key)))
+	w.w.Write(w.buf[:n])
+	w.w.WriteString(r.key)
+	n = binary.PutUvarint(w.buf[:], uint64(r.file))
+	n += binary.PutUvarint(w.buf[n:], uint64(r.line))
+	_, err := w.w.Write(w.buf[:n]) // a bufio.Writer's errors are sticky
+	return err
+}
+
+type runReader struct {
+	r    *bufio.Reader
+	name string
+	rec  record // the current record
+}
+
+// next reads the next record into r.rec, and reports whether there was one.
+func (r *runReader) next() (bool, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading %s: %v", r.name, err)
+	}
+	key := make([]byte, n)
+	if _, err := io.ReadFull(r.r, key); err != nil {
+		return false, fmt.Errorf("reading %s: %v", r.name, err)
+	}
+	file, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return false, fmt.Errorf("reading %s: %v", r.name, err)
+	}
+	line, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return false, fmt.Errorf("reading %s: %v", r.name, err)
+	}
+	r.rec = record{string(key), int(file), int(line)}
+	return true, nil
+}
@@ -5,30 +5,73 @@
 //!+
 
 // Fetch prints the content found at each specified URL.
+// If a transfer is interrupted, it is resumed where it stopped,
+// provided the server supports ranges.  (For downloads to files,
+// with integrity checking, see gopl.io/ch5/fetch.)
 package main
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"os"
+	"strings"
 )
 
 func main() {
 	for _, url := range os.Args[1:] {
-		resp, err := http.Get(url)
-		if err != nil {
+		if err := fetch(url, os.Stdout); err != nil {
 			fmt.Fprintf(os.Stderr, "fetch: %v\n", err)
 			os.Exit(1)
 		}
-		b, err := ioutil.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "fetch: reading %s: %v\n", url, err)
-			os.Exit(1)
-		}
-		fmt.Printf("%s", b)
 	}
 }
 
 //!-
+
+const maxResumes = 5
+
+// fetch copies the content at url to out.  A transfer is resumed
+// with a Range request only if the content has not changed, as
+// shown by its ETag or Last-Modified header.
+func fetch(url string, out io.Writer) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	var n int64          // bytes copied
+	var validator string // for If-Range
+	for resumes := 0; ; resumes++ {
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			validator = resp.Header.Get("ETag")
+			if validator == "" || strings.HasPrefix(validator, "W/") {
+				validator = resp.Header.Get("Last-Modified")
+			}
+			if resp.Header.Get("Accept-Ranges") != "bytes" {
+				validator = "" // can't resume
+			}
+		} else {
+			var start int64
+			fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-", &start)
+			if resp.StatusCode != http.StatusPartialContent || start != n {
+				resp.Body.Close()
+				return fmt.Errorf("reading %s: cannot resume after %d bytes: %s", url, n, resp.Status)
+			}
+		}
+		m, err := io.Copy(out, resp.Body)
+		resp.Body.Close()
+		n += m
+		if err == nil {
+			return nil
+		}
+		if validator == "" || resumes == maxResumes {
+			return fmt.Errorf("reading %s: %v", url, err)
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", n))
+		req.Header.Set("If-Range", validator)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// A result is the outcome of fetching one URL.
+type result struct {
+	URL      string  `json:"url"`
+	Status   int     `json:"status,omitempty"` // of the last response
+	Bytes    int64   `json:"bytes"`
+	Seconds  float64 `json:"seconds"` // latency of the last attempt
+	Attempts int     `json:"attempts"`
+	Err      string  `json:"error,omitempty"`
+}
+
+// A fetcher fetches URLs, retrying after transient errors.
+type fetcher struct {
+	client  *http.Client
+	method  string        // GET or HEAD
+	timeout time.Duration // for each attempt; 0 means none
+	retries int           // maximum number of retries
+	backoff time.Duration // delay before the first retry
+}
+
+// fetchAll fetches the URLs using at most n concurrent requests and
+// sends the results on the returned channel, in order of completion.
+// The channel is closed when all URLs are done.  Once ctx is done,
+// the remaining URLs fail without being fetched.
+func (f *fetcher) fetchAll(ctx context.Context, urls []string, n int) <-chan *result {
+	work := make(chan string)
+	results := make(chan *result)
+	go func() {
+		for _, url := range urls {
+			work <- url
+		}
+		close(work)
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < n && i < len(urls); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range work {
+				results <- f.fetch(ctx, url)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// fetch fetches url, retrying with exponential backoff after a
+// transient error.
+func (f *fetcher) fetch(ctx context.Context, url string) *result {
+	r := &result{URL: url}
+	delay := f.backoff
+	for {
+		r.Attempts++
+		start := time.Now()
+		status, nbytes, err := f.once(ctx, url)
+		r.Seconds = time.Since(start).Seconds()
+		r.Status, r.Bytes = status, nbytes
+		if err == nil || !transient(err) || r.Attempts > f.retries {
+			if err != nil {
+				r.Err = err.Error()
+			}
+			return r
+		}
+		// Wait between delay/2 and delay, so that failing
+		// requests don't all retry at once.
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			r.Err = fmt.Sprintf("%v (after %d attempts)", err, r.Attempts)
+			return r
+		}
+		if delay < math.MaxInt64/2 {
+			delay *= 2
+		}
+	}
+}
+
+// A statusError is an HTTP response with an error status.
+type statusError struct{ code int }
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("%d %s", e.code, http.StatusText(e.code))
+}
+
+// once makes a single request for url and reads the response.
+// For HEAD, the size is that reported by the Content-Length header.
+func (f *fetcher) once(ctx context.Context, url string) (status int, nbytes int64, err error) {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequest(f.method, url, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	resp, err := f.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close() // don't leak resources
+	nbytes, err = io.Copy(ioutil.Discard, resp.Body)
+	if err != nil {
+		return resp.StatusCode, nbytes, fmt.Errorf("while reading %s: %w", url, err)
+	}
+	if f.method == "HEAD" && resp.ContentLength > 0 {
+		nbytes = resp.ContentLength
+	}
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode, nbytes, statusError{resp.StatusCode}
+	}
+	return resp.StatusCode, nbytes, nil
+}
+
+// transient reports whether err may go away if the request is retried:
+// a timeout, a network error, or a status of 429 Too Many Requests,
+// 502 Bad Gateway, 503 Service Unavailable or 504 Gateway Timeout.
+func transient(err error) bool {
+	var se statusError
+	if errors.As(err, &se) {
+		switch se.code {
+		case http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		err = ue.Err // a *url.Error is itself a net.Error
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true // includes timeouts and refused or reset connections
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded)
+}
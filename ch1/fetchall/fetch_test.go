@@ -0,0 +1,187 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newFetcher() *fetcher {
+	return &fetcher{
+		client:  http.DefaultClient,
+		method:  "GET",
+		timeout: time.Second,
+		retries: 2,
+		backoff: time.Millisecond,
+	}
+}
+
+func TestFetch(t *testing.T) {
+	var flaky int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "5")
+		fmt.Fprint(w, "hello")
+	})
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&flaky, 1) < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	})
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	f := newFetcher()
+	for _, test := range []struct {
+		method, path string
+		status       int
+		bytes        int64
+		attempts     int
+		err          string
+	}{
+		{"GET", "/ok", 200, 5, 1, ""},
+		{"HEAD", "/ok", 200, 5, 1, ""},
+		{"GET", "/flaky", 200, 2, 3, ""},
+		{"GET", "/down", 503, 5, 3, "503 Service Unavailable"},
+		{"GET", "/missing", 404, 19, 1, "404 Not Found"}, // not retried
+		{"GET", "/slow", 0, 0, 3, "context deadline exceeded"},
+	} {
+		f.method = test.method
+		f.timeout = time.Second
+		if test.path == "/slow" {
+			f.timeout = 10 * time.Millisecond
+		}
+		r := f.fetch(context.Background(), ts.URL+test.path)
+		if r.Status != test.status || r.Bytes != test.bytes || r.Attempts != test.attempts ||
+			!strings.Contains(r.Err, test.err) || (test.err == "") != (r.Err == "") {
+			t.Errorf("%s %s = %+v, want status %d, %d bytes, %d attempts, error %q",
+				test.method, test.path, r, test.status, test.bytes, test.attempts, test.err)
+		}
+	}
+
+	f.method = "GET"
+	if r := f.fetch(context.Background(), "nosuch://x"); r.Attempts != 1 || r.Err == "" {
+		t.Errorf("unsupported scheme: %+v, want one failed attempt", r)
+	}
+}
+
+func TestFetchAll(t *testing.T) {
+	var mu sync.Mutex
+	active, peak := 0, 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > peak {
+			peak = active
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	var urls []string
+	for i := 0; i < 40; i++ {
+		urls = append(urls, fmt.Sprintf("%s/%d", ts.URL, i))
+	}
+	n := 0
+	for r := range newFetcher().fetchAll(context.Background(), urls, 4) {
+		if r.Err != "" {
+			t.Errorf("%s: %s", r.URL, r.Err)
+		}
+		n++
+	}
+	if n != len(urls) {
+		t.Errorf("got %d results, want %d", n, len(urls))
+	}
+	if peak > 4 {
+		t.Errorf("%d concurrent requests, want at most 4", peak)
+	}
+
+	// After the overall deadline, the remaining URLs fail quickly.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	failed := 0
+	for r := range newFetcher().fetchAll(ctx, append(urls, urls...), 1) {
+		if r.Err != "" {
+			failed++
+		}
+	}
+	if failed == 0 || time.Since(start) > time.Second {
+		t.Errorf("deadline: %d failures after %v", failed, time.Since(start))
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for _, test := range []struct{ p, want float64 }{
+		{0, 1}, {10, 1}, {50, 5}, {90, 9}, {99, 10}, {100, 10},
+	} {
+		if got := percentile(x, test.p); got != test.want {
+			t.Errorf("percentile(1..10, %g) = %g, want %g", test.p, got, test.want)
+		}
+	}
+}
+
+func TestZeroDurations(t *testing.T) {
+	// A zero timeout means no limit, and a zero backoff retries at once.
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 2 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer ts.Close()
+	f := newFetcher()
+	f.timeout, f.backoff = 0, 0
+	if r := f.fetch(context.Background(), ts.URL); r.Err != "" || r.Attempts != 2 {
+		t.Errorf("fetch = %+v, want success after 2 attempts", r)
+	}
+}
+
+func TestCheckFlags(t *testing.T) {
+	for _, test := range []struct {
+		flag *time.Duration
+		val  time.Duration
+		ok   bool
+	}{
+		{timeout, 0, true},
+		{deadline, 0, true},
+		{backoff, 0, true},
+		{timeout, -time.Second, false},
+		{deadline, -time.Second, false},
+		{backoff, -time.Millisecond, false},
+	} {
+		saved := *test.flag
+		*test.flag = test.val
+		if err := checkFlags(); (err == nil) != test.ok {
+			t.Errorf("checkFlags with %v: %v", test.val, err)
+		}
+		*test.flag = saved
+	}
+}
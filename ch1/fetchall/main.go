@@ -5,45 +5,176 @@
 //!+
 
 // Fetchall fetches URLs in parallel and reports their times and sizes.
+// The URLs are taken from the command line or, if there are none,
+// from the standard input, one per line.
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"math"
 	"net/http"
 	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
 	"time"
 )
 
+var (
+	parallel = flag.Int("n", 16, "maximum number of concurrent requests")
+	timeout  = flag.Duration("timeout", 10*time.Second, "time limit for each request (0 means none)")
+	deadline = flag.Duration("deadline", 0, "time limit for the whole run (0 means none)")
+	retries  = flag.Int("retries", 2, "number of retries after a transient error")
+	backoff  = flag.Duration("backoff", 250*time.Millisecond, "delay before the first retry, doubled for each subsequent one")
+	method   = flag.String("method", "GET", "request method: GET or HEAD")
+	format   = flag.String("format", "table", "output format: table, sorted by -sort, or json, one line per URL as it completes")
+	sortBy   = flag.String("sort", "url", "table order: url, time, size or status")
+)
+
 func main() {
+	flag.Parse()
+	if err := checkFlags(); err != nil {
+		fmt.Fprintf(os.Stderr, "fetchall: %v\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	urls := flag.Args()
+	if len(urls) == 0 {
+		var err error
+		if urls, err = readURLs(os.Stdin); err != nil {
+			fatalf("%v", err)
+		}
+	}
+
+	ctx := context.Background()
+	if *deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *deadline)
+		defer cancel()
+	}
+	f := &fetcher{
+		client:  http.DefaultClient,
+		method:  *method,
+		timeout: *timeout,
+		retries: *retries,
+		backoff: *backoff,
+	}
+
 	start := time.Now()
-	ch := make(chan string)
-	for _, url := range os.Args[1:] {
-		go fetch(url, ch) // start a goroutine
+	var results []*result
+	enc := json.NewEncoder(os.Stdout)
+	for r := range f.fetchAll(ctx, urls, *parallel) {
+		if *format == "json" {
+			enc.Encode(r)
+		}
+		results = append(results, r)
 	}
-	for range os.Args[1:] {
-		fmt.Println(<-ch) // receive from channel ch
+	elapsed := time.Since(start)
+
+	summary := os.Stdout
+	if *format == "json" {
+		summary = os.Stderr // keep stdout pure JSON
+	} else {
+		sort.SliceStable(results, func(i, j int) bool {
+			return orders[*sortBy](results[i], results[j])
+		})
+		printTable(os.Stdout, results)
 	}
-	fmt.Printf("%.2fs elapsed\n", time.Since(start).Seconds())
+	printSummary(summary, results, elapsed)
 }
 
-func fetch(url string, ch chan<- string) {
-	start := time.Now()
-	resp, err := http.Get(url)
-	if err != nil {
-		ch <- fmt.Sprint(err) // send to channel ch
-		return
+// checkFlags reports the first invalid flag value.
+func checkFlags() error {
+	switch {
+	case *method != "GET" && *method != "HEAD":
+		return fmt.Errorf("invalid -method %q, want GET or HEAD", *method)
+	case *format != "table" && *format != "json":
+		return fmt.Errorf("invalid -format %q, want table or json", *format)
+	case orders[*sortBy] == nil:
+		return fmt.Errorf("invalid -sort %q, want url, time, size or status", *sortBy)
+	case *parallel < 1:
+		return fmt.Errorf("-n must be positive")
+	case *timeout < 0 || *deadline < 0 || *backoff < 0:
+		return fmt.Errorf("-timeout, -deadline and -backoff must not be negative")
+	case *retries < 0:
+		return fmt.Errorf("-retries must not be negative")
 	}
+	return nil
+}
 
-	nbytes, err := io.Copy(ioutil.Discard, resp.Body)
-	resp.Body.Close() // don't leak resources
-	if err != nil {
-		ch <- fmt.Sprintf("while reading %s: %v", url, err)
-		return
+// readURLs returns the nonblank lines of in.
+func readURLs(in io.Reader) ([]string, error) {
+	var urls []string
+	input := bufio.NewScanner(in)
+	for input.Scan() {
+		if url := strings.TrimSpace(input.Text()); url != "" {
+			urls = append(urls, url)
+		}
 	}
-	secs := time.Since(start).Seconds()
-	ch <- fmt.Sprintf("%.2fs  %7d  %s", secs, nbytes, url)
+	return urls, input.Err()
+}
+
+// orders are the orderings of the table, for -sort.
+var orders = map[string]func(x, y *result) bool{
+	"url":    func(x, y *result) bool { return x.URL < y.URL },
+	"time":   func(x, y *result) bool { return x.Seconds > y.Seconds },
+	"size":   func(x, y *result) bool { return x.Bytes > y.Bytes },
+	"status": func(x, y *result) bool { return x.Status < y.Status },
+}
+
+func printTable(out io.Writer, results []*result) {
+	tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Status\tBytes\tTime\tTries\t  URL\n")
+	for _, r := range results {
+		status := "-"
+		if r.Status != 0 {
+			status = fmt.Sprint(r.Status)
+		}
+		url := r.URL
+		if r.Err != "" {
+			url += " (" + r.Err + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.2fs\t%d\t  %s\n", status, r.Bytes, r.Seconds, r.Attempts, url)
+	}
+	tw.Flush()
+}
+
+// printSummary prints the number of failures and the percentiles of
+// the latencies of the successful fetches.
+func printSummary(out io.Writer, results []*result, elapsed time.Duration) {
+	var secs []float64
+	for _, r := range results {
+		if r.Err == "" {
+			secs = append(secs, r.Seconds)
+		}
+	}
+	fmt.Fprintf(out, "%d URLs, %d failed", len(results), len(results)-len(secs))
+	if len(secs) > 0 {
+		sort.Float64s(secs)
+		fmt.Fprintf(out, "; latency p50 %.2fs, p90 %.2fs, p99 %.2fs, max %.2fs",
+			percentile(secs, 50), percentile(secs, 90), percentile(secs, 99), secs[len(secs)-1])
+	}
+	fmt.Fprintf(out, "\n%.2fs elapsed\n", elapsed.Seconds())
+}
+
+// percentile returns the pth percentile of the sorted non-empty
+// slice x, using the nearest-rank method.
+func percentile(x []float64, p float64) float64 {
+	i := int(math.Ceil(p/100*float64(len(x)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return x[i]
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "fetchall: "+format+"\n", args...)
+	os.Exit(1)
 }
 
 //!-
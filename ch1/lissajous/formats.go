@@ -0,0 +1,155 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// writeSVG writes frame i of the animation as an SVG path.
+func writeSVG(out io.Writer, p *params, i int) error {
+	var buf bytes.Buffer
+	side := 2*p.size + 1
+	fmt.Fprintf(&buf, "<svg xmlns='http://www.w3.org/2000/svg' "+
+		"width='%d' height='%d' viewBox='0 0 %d %d'>\n", side, side, side, side)
+	fmt.Fprintf(&buf, "<rect width='100%%' height='100%%' fill='%s'/>\n", hex(p.palette[0]))
+	fmt.Fprintf(&buf, "<path fill='none' stroke='%s' stroke-width='1' d='",
+		hex(p.palette[1+i%(len(p.palette)-1)]))
+	size := float64(p.size)
+	cmd := "M"
+	p.trace(i, func(x, y float64) {
+		fmt.Fprintf(&buf, "%s%.2f,%.2f", cmd, size+x*size+0.5, size+y*size+0.5)
+		cmd = " "
+	})
+	buf.WriteString("'/>\n</svg>\n")
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+// hex returns the color c in the form #rrggbb.
+func hex(c color.Color) string {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+}
+
+// writeAPNG writes frames as an animated PNG, which browsers display
+// like a GIF that loops forever.  Each frame is encoded by image/png,
+// and its image data is copied into the frame chunks of the APNG;
+// the frames must therefore share the size and palette of the first.
+// See https://wiki.mozilla.org/APNG_Specification.
+func writeAPNG(out io.Writer, frames []*image.Paletted, delay int) error {
+	w := &chunkWriter{w: out}
+	w.write([]byte("\x89PNG\r\n\x1a\n"))
+	seq := uint32(0) // sequence number of fcTL and fdAT chunks
+	for i, img := range frames {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return err
+		}
+		chunks, err := readChunks(buf.Bytes())
+		if err != nil {
+			return err
+		}
+		for _, c := range chunks {
+			switch {
+			case c.typ == "IEND":
+				// written after the last frame
+			case c.typ == "IHDR" && i == 0:
+				w.chunk("IHDR", c.data)
+				w.chunk("acTL", be32(uint32(len(frames)), 0)) // 0 plays means forever
+			case c.typ == "IDAT":
+				if c.first {
+					b := img.Bounds()
+					fctl := be32(seq, uint32(b.Dx()), uint32(b.Dy()), 0, 0)
+					fctl = append(fctl, byte(delay>>8), byte(delay), 0, 100, 0, 0)
+					w.chunk("fcTL", fctl)
+					seq++
+				}
+				if i == 0 {
+					w.chunk("IDAT", c.data)
+				} else {
+					w.chunk("fdAT", append(be32(seq), c.data...))
+					seq++
+				}
+			case i == 0:
+				w.chunk(c.typ, c.data) // e.g., PLTE
+			}
+		}
+	}
+	w.chunk("IEND", nil)
+	return w.err
+}
+
+// A chunk is a chunk of a PNG file.
+type chunk struct {
+	typ   string
+	data  []byte
+	first bool // the first IDAT chunk of the image
+}
+
+// readChunks splits a PNG file into its chunks.
+func readChunks(data []byte) ([]chunk, error) {
+	const sig = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(sig)) {
+		return nil, fmt.Errorf("not a PNG file")
+	}
+	data = data[len(sig):]
+	var chunks []chunk
+	seenIDAT := false
+	for len(data) > 0 {
+		if len(data) < 12 {
+			return nil, fmt.Errorf("truncated PNG chunk")
+		}
+		n := binary.BigEndian.Uint32(data)
+		if uint64(n)+12 > uint64(len(data)) {
+			return nil, fmt.Errorf("truncated PNG chunk")
+		}
+		c := chunk{typ: string(data[4:8]), data: data[8 : 8+n]}
+		if c.typ == "IDAT" {
+			c.first = !seenIDAT
+			seenIDAT = true
+		}
+		chunks = append(chunks, c)
+		data = data[12+n:]
+	}
+	return chunks, nil
+}
+
+// A chunkWriter writes PNG chunks, recording the first error.
+type chunkWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *chunkWriter) write(b []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(b)
+	}
+}
+
+func (w *chunkWriter) chunk(typ string, data []byte) {
+	w.write(be32(uint32(len(data))))
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	w.write([]byte(typ))
+	w.write(data)
+	w.write(be32(crc.Sum32()))
+}
+
+// be32 returns the big-endian encoding of the values.
+func be32(values ...uint32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
@@ -0,0 +1,132 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func get(t *testing.T, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/?"+query, nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func TestReproducible(t *testing.T) {
+	for _, format := range []string{"gif", "png", "svg"} {
+		query := "seed=7&nframes=4&size=20&format=" + format
+		a, b := get(t, query), get(t, query)
+		if a.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", query, a.Code, a.Body)
+		}
+		if !bytes.Equal(a.Body.Bytes(), b.Body.Bytes()) {
+			t.Errorf("%s: different output for the same seed", query)
+		}
+		if c := get(t, "seed=8&nframes=4&size=20&format="+format); bytes.Equal(a.Body.Bytes(), c.Body.Bytes()) {
+			t.Errorf("%s: same output for a different seed", query)
+		}
+	}
+}
+
+func TestAPNG(t *testing.T) {
+	w := get(t, "seed=1&nframes=3&size=10&delay=5&palette=000000,ff0000,00ff00&format=png")
+	data := w.Body.Bytes()
+
+	// Decoders that don't know APNG see the first frame.
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds().Dx(); got != 21 {
+		t.Errorf("width = %d, want 21", got)
+	}
+
+	chunks, err := readChunks(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	var seqs []uint32
+	for _, c := range chunks {
+		if c.typ != "IDAT" && c.typ != "fdAT" || len(types) == 0 || types[len(types)-1] != c.typ {
+			types = append(types, c.typ)
+		}
+		switch c.typ {
+		case "acTL":
+			if n := binary.BigEndian.Uint32(c.data); n != 3 {
+				t.Errorf("acTL frames = %d, want 3", n)
+			}
+		case "fcTL":
+			if delay := binary.BigEndian.Uint16(c.data[20:]); delay != 5 {
+				t.Errorf("fcTL delay = %d, want 5", delay)
+			}
+			fallthrough
+		case "fdAT":
+			seqs = append(seqs, binary.BigEndian.Uint32(c.data))
+		}
+	}
+	want := "IHDR acTL PLTE fcTL IDAT fcTL fdAT fcTL fdAT IEND"
+	if got := strings.Join(types, " "); got != want {
+		t.Errorf("chunks = %s, want %s", got, want)
+	}
+	for i, seq := range seqs {
+		if seq != uint32(i) {
+			t.Errorf("sequence numbers = %v, want 0, 1, 2, ...", seqs)
+			break
+		}
+	}
+
+	// Check the CRCs, which readChunks ignores.
+	data = data[8:]
+	for len(data) > 0 {
+		n := binary.BigEndian.Uint32(data)
+		if crc32.ChecksumIEEE(data[4:8+n]) != binary.BigEndian.Uint32(data[8+n:]) {
+			t.Errorf("bad CRC in %s chunk", data[4:8])
+		}
+		data = data[12+n:]
+	}
+}
+
+func TestSVG(t *testing.T) {
+	w := get(t, "seed=1&size=10&frame=1&palette=ffffff,ff0000,0000ff&format=svg")
+	if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+		t.Errorf("Content-Type = %s", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"viewBox='0 0 21 21'", "fill='#ffffff'", "stroke='#0000ff'", "d='M"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("SVG does not contain %s:\n%.200s", want, body)
+		}
+	}
+}
+
+func TestBadParams(t *testing.T) {
+	for _, test := range []struct{ query, want string }{
+		{"cycles=0", "cycles must be a number between 0.1 and 100"},
+		{"res=x", "res must be a number between"},
+		{"size=-1", "size must be an integer between 1 and 1000"},
+		{"nframes=1000", "nframes must be an integer between 1 and 500"},
+		{"seed=1.5", "seed must be an integer"},
+		{"palette=ffffff", "palette must have between 2 and 256 colors"},
+		{"palette=fff,000", `invalid color "fff"`},
+		{"cycles=100&res=0.0001", "too many points"},
+		{"size=1000&nframes=100", "animation too large"},
+		{"format=jpeg", `unknown format "jpeg"`},
+		{"frame=64", "frame must be between 0 and 63"},
+		{"frame=0&format=gif", "frame is not supported for gif"},
+	} {
+		w := get(t, test.query)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), test.want) {
+			t.Errorf("%s: got %d %q, want 400 %q", test.query, w.Code, w.Body, test.want)
+		}
+	}
+}
@@ -30,57 +30,86 @@ import (
 
 var palette = []color.Color{color.White, color.Black}
 
-const (
-	whiteIndex = 0 // first color in palette
-	blackIndex = 1 // next color in palette
-)
+// params holds the parameters of an animation.  Frame i is drawn
+// in palette[1+i%(len(palette)-1)] on a background of palette[0].
+type params struct {
+	cycles  float64 // number of complete x oscillator revolutions
+	res     float64 // angular resolution
+	size    int     // image canvas covers [-size..+size]
+	nframes int     // number of animation frames
+	delay   int     // delay between frames in 10ms units
+	seed    int64   // seed of the random frequency of the y oscillator
+	palette []color.Color
+}
+
+var defaults = params{
+	cycles:  5,
+	res:     0.001,
+	size:    100,
+	nframes: 64,
+	delay:   8,
+	palette: palette,
+}
 
 func main() {
 	//!-main
 	// The sequence of images is deterministic unless we seed
 	// the pseudo-random number generator using the current time.
 	// Thanks to Randall McPherson for pointing out the omission.
-	rand.Seed(time.Now().UTC().UnixNano())
+	p := defaults
+	p.seed = time.Now().UTC().UnixNano()
 
 	if len(os.Args) > 1 && os.Args[1] == "web" {
 		//!+http
-		handler := func(w http.ResponseWriter, r *http.Request) {
-			lissajous(w)
-		}
 		http.HandleFunc("/", handler)
 		//!-http
 		log.Fatal(http.ListenAndServe("localhost:8000", nil))
 		return
 	}
 	//!+main
-	lissajous(os.Stdout)
+	if err := lissajous(os.Stdout, &p); err != nil {
+		log.Fatal(err)
+	}
 }
 
-func lissajous(out io.Writer) {
-	const (
-		cycles  = 5     // number of complete x oscillator revolutions
-		res     = 0.001 // angular resolution
-		size    = 100   // image canvas covers [-size..+size]
-		nframes = 64    // number of animation frames
-		delay   = 8     // delay between frames in 10ms units
-	)
-	freq := rand.Float64() * 3.0 // relative frequency of y oscillator
-	anim := gif.GIF{LoopCount: nframes}
-	phase := 0.0 // phase difference
-	for i := 0; i < nframes; i++ {
-		rect := image.Rect(0, 0, 2*size+1, 2*size+1)
-		img := image.NewPaletted(rect, palette)
-		for t := 0.0; t < cycles*2*math.Pi; t += res {
-			x := math.Sin(t)
-			y := math.Sin(t*freq + phase)
-			img.SetColorIndex(size+int(x*size+0.5), size+int(y*size+0.5),
-				blackIndex)
-		}
-		phase += 0.1
-		anim.Delay = append(anim.Delay, delay)
+func lissajous(out io.Writer, p *params) error {
+	anim := gif.GIF{LoopCount: p.nframes}
+	for _, img := range p.frames() {
+		anim.Delay = append(anim.Delay, p.delay)
 		anim.Image = append(anim.Image, img)
 	}
-	gif.EncodeAll(out, &anim) // NOTE: ignoring encoding errors
+	return gif.EncodeAll(out, &anim)
+}
+
+// frames returns the frames of the animation.
+func (p *params) frames() []*image.Paletted {
+	var frames []*image.Paletted
+	for i := 0; i < p.nframes; i++ {
+		frames = append(frames, p.frame(i))
+	}
+	return frames
+}
+
+// frame returns frame i of the animation.
+func (p *params) frame(i int) *image.Paletted {
+	rect := image.Rect(0, 0, 2*p.size+1, 2*p.size+1)
+	img := image.NewPaletted(rect, p.palette)
+	index := uint8(1 + i%(len(p.palette)-1))
+	size := float64(p.size)
+	p.trace(i, func(x, y float64) {
+		img.SetColorIndex(p.size+int(x*size+0.5), p.size+int(y*size+0.5), index)
+	})
+	return img
+}
+
+// trace calls visit for each point (x, y) of the figure in frame i,
+// where x and y are in [-1, 1].
+func (p *params) trace(i int, visit func(x, y float64)) {
+	freq := rand.New(rand.NewSource(p.seed)).Float64() * 3.0 // relative frequency of y oscillator
+	phase := float64(i) * 0.1                                // phase difference
+	for t := 0.0; t < p.cycles*2*math.Pi; t += p.res {
+		visit(math.Sin(t), math.Sin(t*freq+phase))
+	}
 }
 
 //!-main
@@ -0,0 +1,170 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"image/png"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handler serves an animation whose parameters are taken from the
+// query, for example:
+//
+//	/?cycles=3&size=200&seed=42&palette=000000,ff0000,00ff00&format=png
+//
+// The parameters are those of params, plus format, which is one of
+// gif (the default), png, for an animated PNG, or svg, for a single
+// frame as a path, and frame, which selects a frame to be served as
+// a still PNG or SVG.  Without a seed, the figure is random.
+func handler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := parseParams(r.Form)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	frame := -1 // all frames
+	if s := r.Form.Get("frame"); s != "" {
+		frame, err = strconv.Atoi(s)
+		if err != nil || frame < 0 || frame >= p.nframes {
+			http.Error(w, fmt.Sprintf("frame must be between 0 and %d", p.nframes-1), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+	switch format := r.Form.Get("format"); format {
+	case "", "gif":
+		if frame >= 0 {
+			http.Error(w, "frame is not supported for gif", http.StatusBadRequest)
+			return
+		}
+		contentType = "image/gif"
+		err = lissajous(&buf, p)
+	case "png":
+		contentType = "image/png"
+		if frame >= 0 {
+			err = png.Encode(&buf, p.frame(frame))
+		} else {
+			err = writeAPNG(&buf, p.frames(), p.delay)
+		}
+	case "svg":
+		contentType = "image/svg+xml"
+		if frame < 0 {
+			frame = 0
+		}
+		err = writeSVG(&buf, p, frame)
+	default:
+		http.Error(w, fmt.Sprintf("unknown format %q (want gif, png or svg)", format), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
+
+// Limits on the cost of a single request.
+const (
+	maxPoints = 1e6 // per frame
+	maxPixels = 5e7 // in all frames
+)
+
+// parseParams returns the parameters in form, using defaults for
+// those that are absent.
+func parseParams(form url.Values) (*params, error) {
+	p := defaults
+	p.seed = time.Now().UTC().UnixNano()
+
+	floats := []struct {
+		name     string
+		v        *float64
+		min, max float64
+	}{
+		{"cycles", &p.cycles, 0.1, 100},
+		{"res", &p.res, 1e-5, 1},
+	}
+	for _, f := range floats {
+		if s := form.Get(f.name); s != "" {
+			x, err := strconv.ParseFloat(s, 64)
+			if err != nil || !(x >= f.min && x <= f.max) {
+				return nil, fmt.Errorf("%s must be a number between %g and %g", f.name, f.min, f.max)
+			}
+			*f.v = x
+		}
+	}
+	ints := []struct {
+		name     string
+		v        *int
+		min, max int
+	}{
+		{"size", &p.size, 1, 1000},
+		{"nframes", &p.nframes, 1, 500},
+		{"delay", &p.delay, 0, 6000},
+	}
+	for _, f := range ints {
+		if s := form.Get(f.name); s != "" {
+			x, err := strconv.Atoi(s)
+			if err != nil || x < f.min || x > f.max {
+				return nil, fmt.Errorf("%s must be an integer between %d and %d", f.name, f.min, f.max)
+			}
+			*f.v = x
+		}
+	}
+	if s := form.Get("seed"); s != "" {
+		seed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("seed must be an integer")
+		}
+		p.seed = seed
+	}
+	if s := form.Get("palette"); s != "" {
+		pal, err := parsePalette(s)
+		if err != nil {
+			return nil, err
+		}
+		p.palette = pal
+	}
+
+	if p.cycles*2*math.Pi/p.res > maxPoints {
+		return nil, fmt.Errorf("too many points: cycles/res must be at most %.0f", maxPoints/(2*math.Pi))
+	}
+	side := float64(2*p.size + 1)
+	if float64(p.nframes)*side*side > maxPixels {
+		return nil, fmt.Errorf("animation too large: nframes×(2×size+1)² must be at most %.0f", maxPixels)
+	}
+	return &p, nil
+}
+
+// parsePalette parses a comma-separated list of 2 to 256 colors in
+// hexadecimal, e.g., ffffff,000000.
+func parsePalette(s string) ([]color.Color, error) {
+	var pal []color.Color
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimPrefix(strings.TrimSpace(c), "#")
+		rgb, err := strconv.ParseUint(c, 16, 32)
+		if err != nil || len(c) != 6 {
+			return nil, fmt.Errorf("invalid color %q in palette, want rrggbb", c)
+		}
+		pal = append(pal, color.RGBA{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), 0xff})
+	}
+	if len(pal) < 2 || len(pal) > 256 {
+		return nil, fmt.Errorf("palette must have between 2 and 256 colors")
+	}
+	return pal, nil
+}
@@ -0,0 +1,284 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+// Package metrics records the requests made to HTTP handlers: their
+// number by path, method and status code, the number in progress, and
+// a histogram of their latencies.  It serves the metrics in the
+// Prometheus text format and as JSON.
+//
+// Typical use:
+//
+//	reg := metrics.New()
+//	reg.HandleFunc(http.DefaultServeMux, "/", handler)
+//	http.HandleFunc("/metrics", reg.ServeMetrics)
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the buckets of
+// the latency histograms.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// A Registry holds the metrics of the handlers it wraps.
+// It is safe for concurrent use.
+type Registry struct {
+	buckets []float64
+
+	mu        sync.Mutex // guards the fields below
+	inFlight  int
+	requests  map[requestKey]uint64
+	latencies map[latencyKey]*histogram
+}
+
+type requestKey struct {
+	path, method string
+	code         int
+}
+
+type latencyKey struct{ path, method string }
+
+type histogram struct {
+	counts []uint64 // counts[i] is the number of observations in bucket i; the last is +Inf
+	sum    float64
+	count  uint64
+}
+
+// New returns a registry whose histograms use DefaultBuckets.
+func New() *Registry {
+	return NewBuckets(DefaultBuckets)
+}
+
+// NewBuckets returns a registry whose histograms have buckets with
+// the given upper bounds, which must be in increasing order.
+func NewBuckets(buckets []float64) *Registry {
+	return &Registry{
+		buckets:   buckets,
+		requests:  make(map[requestKey]uint64),
+		latencies: make(map[latencyKey]*histogram),
+	}
+}
+
+// Handler returns a handler that calls h and records its requests
+// under the given path, which is normally the pattern with which h
+// is registered.  (Recording each URL.Path would let any client
+// create an unbounded number of series.)
+func (reg *Registry) Handler(path string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reg.mu.Lock()
+		reg.inFlight++
+		reg.mu.Unlock()
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			secs := time.Since(start).Seconds()
+			code := sw.code
+			if code == 0 {
+				code = http.StatusOK
+			}
+			if v := recover(); v != nil {
+				code = http.StatusInternalServerError
+				defer panic(v) // after recording
+			}
+			reg.record(path, method(r), code, secs)
+		}()
+		h.ServeHTTP(sw, r)
+	})
+}
+
+// method returns the method of r, or "OTHER" if it is not one of
+// the standard methods, for the same reason.
+func method(r *http.Request) string {
+	switch r.Method {
+	case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "CONNECT", "OPTIONS", "TRACE":
+		return r.Method
+	}
+	return "OTHER"
+}
+
+// HandleFunc registers f with mux for the given pattern, recording
+// its requests under the pattern.
+func (reg *Registry) HandleFunc(mux *http.ServeMux, pattern string, f func(http.ResponseWriter, *http.Request)) {
+	mux.Handle(pattern, reg.Handler(pattern, http.HandlerFunc(f)))
+}
+
+func (reg *Registry) record(path, method string, code int, secs float64) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.inFlight--
+	reg.requests[requestKey{path, method, code}]++
+	k := latencyKey{path, method}
+	h := reg.latencies[k]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(reg.buckets)+1)}
+		reg.latencies[k] = h
+	}
+	i := sort.SearchFloat64s(reg.buckets, secs) // first bucket with bound >= secs
+	h.counts[i]++
+	h.sum += secs
+	h.count++
+}
+
+// A statusWriter records the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	code int // 0 until the header is written
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush lets handlers that stream their responses flush them.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.code == 0 {
+			w.code = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// ServeMetrics writes the metrics in the Prometheus text format.
+func (reg *Registry) ServeMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	reg.WriteText(w)
+}
+
+// WriteText writes the metrics to out in the Prometheus text format.
+// Series are sorted by their labels.
+func (reg *Registry) WriteText(out io.Writer) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	w := bufio.NewWriter(out)
+
+	fmt.Fprintln(w, "# HELP http_requests_total Number of HTTP requests completed.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+	for _, k := range reg.requestKeys() {
+		fmt.Fprintf(w, "http_requests_total{path=%s,method=%s,code=\"%d\"} %d\n",
+			quote(k.path), quote(k.method), k.code, reg.requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP http_requests_in_flight Number of HTTP requests in progress.")
+	fmt.Fprintln(w, "# TYPE http_requests_in_flight gauge")
+	fmt.Fprintf(w, "http_requests_in_flight %d\n", reg.inFlight)
+
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds Latency of HTTP requests.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds histogram")
+	for _, k := range reg.latencyKeys() {
+		h := reg.latencies[k]
+		labels := fmt.Sprintf("path=%s,method=%s", quote(k.path), quote(k.method))
+		var cumulative uint64
+		for i, n := range h.counts {
+			cumulative += n
+			le := "+Inf"
+			if i < len(reg.buckets) {
+				le = strconv.FormatFloat(reg.buckets[i], 'g', -1, 64)
+			}
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, le, cumulative)
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{%s} %g\n", labels, h.sum)
+		fmt.Fprintf(w, "http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+	return w.Flush()
+}
+
+// quote returns s as a quoted label value.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// A Count is the number of requests with a path, method and status code.
+type Count struct {
+	Path   string `json:"path"`
+	Method string `json:"method"`
+	Code   int    `json:"code"`
+	Count  uint64 `json:"count"`
+}
+
+// A Summary is the JSON form of the request counts.
+type Summary struct {
+	Total    uint64  `json:"total"`
+	InFlight int     `json:"in_flight"`
+	Requests []Count `json:"requests"`
+}
+
+// Summary returns the request counts.
+func (reg *Registry) Summary() Summary {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	s := Summary{InFlight: reg.inFlight, Requests: []Count{}}
+	for _, k := range reg.requestKeys() {
+		n := reg.requests[k]
+		s.Total += n
+		s.Requests = append(s.Requests, Count{k.path, k.method, k.code, n})
+	}
+	return s
+}
+
+// ServeCount writes the request counts as JSON.
+func (reg *Registry) ServeCount(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(reg.Summary())
+}
+
+// requestKeys returns the keys of reg.requests, sorted.
+// reg.mu must be held.
+func (reg *Registry) requestKeys() []requestKey {
+	var keys []requestKey
+	for k := range reg.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		x, y := keys[i], keys[j]
+		if x.path != y.path {
+			return x.path < y.path
+		}
+		if x.method != y.method {
+			return x.method < y.method
+		}
+		return x.code < y.code
+	})
+	return keys
+}
+
+// latencyKeys returns the keys of reg.latencies, sorted.
+// reg.mu must be held.
+func (reg *Registry) latencyKeys() []latencyKey {
+	var keys []latencyKey
+	for k := range reg.latencies {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		x, y := keys[i], keys[j]
+		if x.path != y.path {
+			return x.path < y.path
+		}
+		return x.method < y.method
+	})
+	return keys
+}
@@ -0,0 +1,108 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	reg := NewBuckets([]float64{0.5, 1})
+	mux := http.NewServeMux()
+	started, release := make(chan bool), make(chan bool)
+	reg.HandleFunc(mux, "/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	})
+	reg.HandleFunc(mux, "/slow", func(w http.ResponseWriter, r *http.Request) {
+		started <- true
+		<-release
+	})
+	reg.HandleFunc(mux, "/count", reg.ServeCount)
+	mux.HandleFunc("/metrics", reg.ServeMetrics)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	do("GET", "/")
+	do("GET", "/a/b") // recorded as "/"
+	do("POST", "/")
+	do("BREW", "/")
+	do("GET", "/missing")
+	done := make(chan bool)
+	go func() { do("GET", "/slow"); done <- true }()
+	<-started
+
+	w := do("GET", "/count")
+	var got Summary
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := Summary{Total: 5, InFlight: 2, Requests: []Count{ // /count and /slow
+		{"/", "GET", 200, 2},
+		{"/", "GET", 404, 1},
+		{"/", "OTHER", 200, 1},
+		{"/", "POST", 200, 1},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("/count = %+v, want %+v", got, want)
+	}
+	release <- true
+	<-done
+
+	text := do("GET", "/metrics").Body.String()
+	for _, line := range []string{
+		"# TYPE http_requests_total counter",
+		`http_requests_total{path="/",method="GET",code="404"} 1`,
+		`http_requests_total{path="/count",method="GET",code="200"} 1`,
+		"http_requests_in_flight 0",
+		"# TYPE http_request_duration_seconds histogram",
+		`http_request_duration_seconds_bucket{path="/",method="GET",le="0.5"} 3`,
+		`http_request_duration_seconds_bucket{path="/",method="GET",le="1"} 3`,
+		`http_request_duration_seconds_bucket{path="/",method="GET",le="+Inf"} 3`,
+		`http_request_duration_seconds_count{path="/",method="GET"} 3`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("/metrics does not contain %s:\n%s", line, text)
+		}
+	}
+}
+
+func TestPanic(t *testing.T) {
+	reg := New()
+	h := reg.Handler("/p", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("oops")
+	}))
+	func() {
+		defer func() {
+			if v := recover(); v != "oops" {
+				t.Errorf("recovered %v, want oops", v)
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/p", nil))
+	}()
+	if got := reg.Summary().Requests; len(got) != 1 || got[0].Code != 500 {
+		t.Errorf("requests = %+v, want one 500", got)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	reg := New()
+	reg.record("/a\"b\\c\nd", "GET", 200, 0)
+	var buf bytes.Buffer
+	reg.WriteText(&buf)
+	want := `http_requests_total{path="/a\"b\\c\nd",method="GET",code="200"} 1`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("got:\n%s\nwant %s", &buf, want)
+	}
+}
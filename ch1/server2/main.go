@@ -5,37 +5,30 @@
 //!+
 
 // Server2 is a minimal "echo" and counter server.
+// It serves metrics of its requests at /metrics, in the Prometheus
+// text format, and their counts at /count, as JSON.
 package main
 
 import (
 	"fmt"
 	"log"
 	"net/http"
-	"sync"
+
+	"gopl.io/ch1/metrics"
 )
 
-var mu sync.Mutex
-var count int
+var reg = metrics.New()
 
 func main() {
-	http.HandleFunc("/", handler)
-	http.HandleFunc("/count", counter)
+	reg.HandleFunc(http.DefaultServeMux, "/", handler)
+	reg.HandleFunc(http.DefaultServeMux, "/count", reg.ServeCount)
+	http.HandleFunc("/metrics", reg.ServeMetrics)
 	log.Fatal(http.ListenAndServe("localhost:8000", nil))
 }
 
 // handler echoes the Path component of the requested URL.
 func handler(w http.ResponseWriter, r *http.Request) {
-	mu.Lock()
-	count++
-	mu.Unlock()
 	fmt.Fprintf(w, "URL.Path = %q\n", r.URL.Path)
 }
 
-// counter echoes the number of calls so far.
-func counter(w http.ResponseWriter, r *http.Request) {
-	mu.Lock()
-	fmt.Fprintf(w, "Count %d\n", count)
-	mu.Unlock()
-}
-
 //!-
@@ -27,10 +27,26 @@ func Example_expr() {
 	// e.fn = "sqrt"
 	// e.args[0].type = eval.binary
 	// e.args[0].value.op = 47
-	// e.args[0].value.x.type = eval.Var
-	// e.args[0].value.x.value = "A"
-	// e.args[0].value.y.type = eval.Var
-	// e.args[0].value.y.value = "pi"
+	// e.args[0].value.x.type = eval.ident
+	// e.args[0].value.x.value.name = "A"
+	// e.args[0].value.x.value.pos.Filename = ""
+	// e.args[0].value.x.value.pos.Offset = 5
+	// e.args[0].value.x.value.pos.Line = 1
+	// e.args[0].value.x.value.pos.Column = 6
+	// e.args[0].value.y.type = eval.ident
+	// e.args[0].value.y.value.name = "pi"
+	// e.args[0].value.y.value.pos.Filename = ""
+	// e.args[0].value.y.value.pos.Offset = 9
+	// e.args[0].value.y.value.pos.Line = 1
+	// e.args[0].value.y.value.pos.Column = 10
+	// e.args[0].value.pos.Filename = ""
+	// e.args[0].value.pos.Offset = 7
+	// e.args[0].value.pos.Line = 1
+	// e.args[0].value.pos.Column = 8
+	// e.pos.Filename = ""
+	// e.pos.Offset = 0
+	// e.pos.Line = 1
+	// e.pos.Column = 1
 }
 
 func Example_slice() {
@@ -0,0 +1,74 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	plan9 "image/color/palette"
+)
+
+// animate writes an animation of n frames, each width×height pixels,
+// zooming into the center of v from a view w0 units wide to one w1
+// units wide.  The scale changes by the same factor between each pair
+// of frames.
+//
+// If out ends in .gif or is empty (for standard output), the frames
+// form an animated GIF.  Otherwise out is a pattern such as
+// frame%03d.png, and each frame is written to its own PNG file.
+func animate(out string, v *view, w0, w1 float64, n, width, height int, fr fractal, pal palette) error {
+	asGIF := out == "" || strings.HasSuffix(out, ".gif")
+	if !asGIF && !strings.Contains(out, "%") {
+		return fmt.Errorf("output %q is neither a .gif file nor a pattern such as frame%%03d.png", out)
+	}
+	var anim gif.GIF
+	for k := 0; k < n; k++ {
+		t := 0.0
+		if n > 1 {
+			t = float64(k) / float64(n-1)
+		}
+		frame := *v
+		frame.width = w0 * math.Pow(w1/w0, t)
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		render(img, frame.width, *samples, frame.colorFunc(fr, pal))
+		if asGIF {
+			p := image.NewPaletted(img.Bounds(), plan9.Plan9)
+			draw.FloydSteinberg.Draw(p, p.Bounds(), img, image.ZP)
+			anim.Image = append(anim.Image, p)
+			anim.Delay = append(anim.Delay, *delay)
+			continue
+		}
+		if err := writeFile(fmt.Sprintf(out, k), func(w io.Writer) error { return png.Encode(w, img) }); err != nil {
+			return err
+		}
+	}
+	if !asGIF {
+		return nil
+	}
+	if out == "" {
+		return gif.EncodeAll(os.Stdout, &anim)
+	}
+	return writeFile(out, func(w io.Writer) error { return gif.EncodeAll(w, &anim) })
+}
+
+// writeFile creates the named file and writes it using write.
+func writeFile(name string, write func(w io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,235 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"image"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// These benchmarks compare the speed and accuracy of the Mandelbrot
+// iteration using four representations of numbers.  Each reports, as
+// "mismatch%", the percentage of pixels whose escape count differs
+// from that computed with big.Float at twice the precision.
+//
+//	go test -bench=Precision gopl.io/ch3/mandelbrot
+//
+// big.Rat is exact, but the size of its numbers doubles with each
+// iteration, so it is benchmarked only with a few iterations.
+
+func escapeComplex64(c complex64, n int) int {
+	var z complex64
+	for i := 0; i < n; i++ {
+		z = z*z + c
+		if real(z)*real(z)+imag(z)*imag(z) > 4 {
+			return i
+		}
+	}
+	return n
+}
+
+func escapeComplex128(c complex128, n int) int {
+	var z complex128
+	for i := 0; i < n; i++ {
+		z = z*z + c
+		if real(z)*real(z)+imag(z)*imag(z) > 4 {
+			return i
+		}
+	}
+	return n
+}
+
+func escapeFloat(cx, cy *big.Float, n int) int {
+	prec := cx.Prec()
+	newFloat := func() *big.Float { return new(big.Float).SetPrec(prec) }
+	x, y, x2, y2, xy := newFloat(), newFloat(), newFloat(), newFloat(), newFloat()
+	r2, four := newFloat(), big.NewFloat(4)
+	for i := 0; i < n; i++ {
+		x2.Mul(x, x)
+		y2.Mul(y, y)
+		xy.Mul(x, y)
+		x.Sub(x2, y2).Add(x, cx)
+		y.Add(xy, xy).Add(y, cy)
+		if r2.Mul(x, x).Add(r2, y2.Mul(y, y)).Cmp(four) > 0 {
+			return i
+		}
+	}
+	return n
+}
+
+func escapeRat(cx, cy *big.Rat, n int) int {
+	x, y, x2, y2, xy := new(big.Rat), new(big.Rat), new(big.Rat), new(big.Rat), new(big.Rat)
+	r2, four := new(big.Rat), big.NewRat(4, 1)
+	for i := 0; i < n; i++ {
+		x2.Mul(x, x)
+		y2.Mul(y, y)
+		xy.Mul(x, y)
+		x.Sub(x2, y2).Add(x, cx)
+		y.Add(xy, xy).Add(y, cy)
+		if r2.Mul(x, x).Add(r2, y2.Mul(y, y)).Cmp(four) > 0 {
+			return i
+		}
+	}
+	return n
+}
+
+// A scene is a view to be rendered at a given size and number of iterations.
+type scene struct {
+	name       string
+	center     string
+	width      float64
+	size, iter int
+}
+
+var scenes = []scene{
+	{"whole", "0,0", 4, 16, 10},
+	{"shallow", "-0.75,0.1", 0.5, 32, 100},
+	{"zoom", "-0.743643887037158704752191506114774,0.131825904205311970493132056385139", 1e-6, 32, 1000},
+	{"deep", "-0.743643887037158704752191506114774,0.131825904205311970493132056385139", 1e-9, 16, 3000},
+}
+
+// A numType computes the escape count of each pixel of a scene.
+type numType struct {
+	name   string
+	counts func(s scene, prec uint) []int
+}
+
+var numTypes = []numType{
+	{"complex64", func(s scene, _ uint) []int {
+		cx, cy := s.origin(64)
+		x, _ := cx.Float64()
+		y, _ := cy.Float64()
+		c0 := complex64(complex(x, y))
+		return grid(s, func(dx, dy float64) int {
+			return escapeComplex64(c0+complex64(complex(dx, dy)), s.iter)
+		})
+	}},
+	{"complex128", func(s scene, _ uint) []int {
+		cx, cy := s.origin(64)
+		x, _ := cx.Float64()
+		y, _ := cy.Float64()
+		c0 := complex(x, y)
+		return grid(s, func(dx, dy float64) int {
+			return escapeComplex128(c0+complex(dx, dy), s.iter)
+		})
+	}},
+	{"big.Float", func(s scene, prec uint) []int {
+		cx, cy := s.origin(prec)
+		return grid(s, func(dx, dy float64) int {
+			x := new(big.Float).SetPrec(prec).SetFloat64(dx)
+			y := new(big.Float).SetPrec(prec).SetFloat64(dy)
+			return escapeFloat(x.Add(x, cx), y.Add(y, cy), s.iter)
+		})
+	}},
+	{"big.Rat", func(s scene, prec uint) []int {
+		cx, cy := s.origin(prec)
+		rx, _ := cx.Rat(nil)
+		ry, _ := cy.Rat(nil)
+		return grid(s, func(dx, dy float64) int {
+			x := new(big.Rat).SetFloat64(dx)
+			y := new(big.Rat).SetFloat64(dy)
+			return escapeRat(x.Add(x, rx), y.Add(y, ry), s.iter)
+		})
+	}},
+}
+
+// origin returns the center of s with the given precision.
+func (s scene) origin(prec uint) (cx, cy *big.Float) {
+	parts := strings.Split(s.center, ",")
+	cx, _, err := big.ParseFloat(parts[0], 10, prec, big.ToNearestEven)
+	if err != nil {
+		panic(err)
+	}
+	cy, _, err = big.ParseFloat(parts[1], 10, prec, big.ToNearestEven)
+	if err != nil {
+		panic(err)
+	}
+	return cx, cy
+}
+
+// grid returns f(dx, dy) for the offset of each pixel of s from its center.
+func grid(s scene, f func(dx, dy float64) int) []int {
+	var counts []int
+	scale := s.width / float64(s.size)
+	for py := 0; py < s.size; py++ {
+		for px := 0; px < s.size; px++ {
+			dx := (float64(px) + 0.5 - float64(s.size)/2) * scale
+			dy := (float64(py) + 0.5 - float64(s.size)/2) * scale
+			counts = append(counts, f(dx, dy))
+		}
+	}
+	return counts
+}
+
+var (
+	referenceOnce sync.Once
+	references    map[string][]int // by scene name
+)
+
+func reference(s scene) []int {
+	referenceOnce.Do(func() {
+		references = make(map[string][]int)
+		for _, s := range scenes {
+			references[s.name] = numTypes[2].counts(s, 2*precisionFor(s))
+		}
+	})
+	return references[s.name]
+}
+
+// precisionFor returns the big.Float precision that render would use for s.
+func precisionFor(s scene) uint {
+	return precision(s.width) // the function in view.go
+}
+
+func BenchmarkPrecision(b *testing.B) {
+	for _, s := range scenes {
+		for _, p := range numTypes {
+			s, p := s, p
+			b.Run(s.name+"/"+p.name, func(b *testing.B) {
+				if p.name == "big.Rat" && s.iter > 10 {
+					b.Skip("too many iterations for big.Rat")
+				}
+				want := reference(s)
+				b.ResetTimer()
+				var got []int
+				for i := 0; i < b.N; i++ {
+					got = p.counts(s, precisionFor(s))
+				}
+				b.StopTimer()
+				mismatch := 0
+				for i := range got {
+					if got[i] != want[i] {
+						mismatch++
+					}
+				}
+				b.ReportMetric(100*float64(mismatch)/float64(len(got)), "mismatch%")
+			})
+		}
+	}
+}
+
+// BenchmarkRender measures the parallel renderer on the default view.
+func BenchmarkRender(b *testing.B) {
+	v, _ := parseView("0,0", 1)
+	f := v.colorFunc(fractal{name: "mandelbrot"}, palettes["gray"])
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for i := 0; i < b.N; i++ {
+		render(img, v.width, 1, f)
+	}
+}
+
+// BenchmarkRenderRows measures the same work done by one goroutine.
+func BenchmarkRenderRows(b *testing.B) {
+	v, _ := parseView("0,0", 1)
+	f := v.colorFunc(fractal{name: "mandelbrot"}, palettes["gray"])
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for i := 0; i < b.N; i++ {
+		for py := 0; py < 256; py++ {
+			renderRow(img, py, v.width, 1, f)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import "container/list"
+
+// An lru is a cache of tiles that holds at most max entries,
+// discarding the least recently used entry to make room.
+// It is not concurrency-safe.
+type lru struct {
+	max   int
+	ll    *list.List // of *lruItem, most recently used first
+	items map[tile]*list.Element
+}
+
+type lruItem struct {
+	key tile
+	e   *entry
+}
+
+func newLRU(max int) *lru {
+	return &lru{max: max, ll: list.New(), items: make(map[tile]*list.Element)}
+}
+
+// get returns the entry for key, if present, and marks it used.
+func (c *lru) get(key tile) (*entry, bool) {
+	if elem, ok := c.items[key]; ok {
+		c.ll.MoveToFront(elem)
+		return elem.Value.(*lruItem).e, true
+	}
+	return nil, false
+}
+
+// add adds an entry for key, which must not be present.
+func (c *lru) add(key tile, e *entry) {
+	c.items[key] = c.ll.PushFront(&lruItem{key, e})
+	for c.ll.Len() > c.max {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+// remove removes the entry for key, if it is e.
+func (c *lru) remove(key tile, e *entry) {
+	if elem, ok := c.items[key]; ok && elem.Value.(*lruItem).e == e {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+// len returns the number of entries.
+func (c *lru) len() int { return c.ll.Len() }
@@ -0,0 +1,26 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import "testing"
+
+func TestLRU(t *testing.T) {
+	c := newLRU(2)
+	a, b, d := tile{1, 0, 0}, tile{1, 0, 1}, tile{1, 1, 0}
+	c.add(a, &entry{})
+	c.add(b, &entry{})
+	if _, ok := c.get(a); !ok { // a is now more recent than b
+		t.Fatal("a missing")
+	}
+	c.add(d, &entry{})
+	if _, ok := c.get(b); ok {
+		t.Error("b was not evicted")
+	}
+	if _, ok := c.get(a); !ok {
+		t.Error("a was evicted")
+	}
+	if c.len() != 2 {
+		t.Errorf("len = %d, want 2", c.len())
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// fractals lists the names accepted by the -fractal flag.
+var fractals = []string{"mandelbrot", "julia", "ship", "newton", "acos", "sqrt"}
+
+// A fractal identifies the function that colors each point.
+type fractal struct {
+	name   string     // one of fractals
+	c      complex128 // the constant of a Julia set
+	degree int        // the degree n of z^n - 1, for newton
+}
+
+func parseFractal(name, c string, degree int) (fractal, error) {
+	fr := fractal{name: name, degree: degree}
+	switch name {
+	case "julia":
+		var err error
+		if fr.c, err = parseComplex(c); err != nil {
+			return fr, err
+		}
+	case "newton":
+		if degree < 2 {
+			return fr, fmt.Errorf("invalid degree %d, want at least 2", degree)
+		}
+	}
+	for _, f := range fractals {
+		if f == name {
+			return fr, nil
+		}
+	}
+	return fr, fmt.Errorf("unknown fractal %q, want one of %s", name, strings.Join(fractals, ", "))
+}
+
+// parseComplex parses a complex number of the form re,im.
+func parseComplex(s string) (complex128, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) == 2 {
+		re, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		im, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err1 == nil && err2 == nil {
+			return complex(re, im), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid complex number %q, want re,im", s)
+}
+
+// julia is like escape, but iterates z = z² + c from z0 for a fixed c.
+func julia(z0, c complex128, n int) float64 {
+	z := z0
+	for i := 0; i < n; i++ {
+		z = z*z + c
+		if r2 := real(z)*real(z) + imag(z)*imag(z); r2 > bailout*bailout {
+			return smooth(i, r2)
+		}
+	}
+	return -1
+}
+
+// ship is like escape for the "burning ship" fractal, which takes the
+// absolute values of the parts of z before squaring it.  With the
+// imaginary axis pointing down the image, the ship is upright.
+func ship(c complex128, n int) float64 {
+	var z complex128
+	for i := 0; i < n; i++ {
+		z = complex(math.Abs(real(z)), math.Abs(imag(z)))
+		z = z*z + c
+		if r2 := real(z)*real(z) + imag(z)*imag(z); r2 > bailout*bailout {
+			return smooth(i, r2)
+		}
+	}
+	return -1
+}
+
+// hsv returns the color with hue h, saturation s and value v, all in [0, 1].
+func hsv(h, s, v float64) color.RGBA {
+	h = math.Mod(h, 1) * 6
+	i := math.Floor(h)
+	f := h - i
+	p, q, t := v*(1-s), v*(1-s*f), v*(1-s*(1-f))
+	var r, g, b float64
+	switch int(i) {
+	case 0:
+		r, g, b = v, t, p
+	case 1:
+		r, g, b = q, v, p
+	case 2:
+		r, g, b = p, v, t
+	case 3:
+		r, g, b = p, q, v
+	case 4:
+		r, g, b = t, p, v
+	default:
+		r, g, b = v, p, q
+	}
+	return color.RGBA{uint8(r*255 + 0.5), uint8(g*255 + 0.5), uint8(b*255 + 0.5), 0xff}
+}
+
+func toRGBA(c color.Color) color.RGBA {
+	return color.RGBAModel.Convert(c).(color.RGBA)
+}
@@ -0,0 +1,91 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"image/gif"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		w, h int
+		ok   bool
+	}{
+		{"1024", 1024, 1024, true},
+		{"640x480", 640, 480, true},
+		{"640x", 0, 0, false},
+		{"0", 0, 0, false},
+		{"1x2x3", 0, 0, false},
+	} {
+		w, h, err := parseSize(test.s)
+		if w != test.w || h != test.h || (err == nil) != test.ok {
+			t.Errorf("parseSize(%q) = %d, %d, %v", test.s, w, h, err)
+		}
+	}
+}
+
+func TestRunSamples(t *testing.T) {
+	defer func(ss int) { *samples = ss }(*samples)
+	for _, ss := range []int{0, -1} {
+		*samples = ss
+		if err := run(); err == nil {
+			t.Errorf("run with -ss %d succeeded, want error", ss)
+		}
+	}
+}
+
+func TestNewtonRoots(t *testing.T) {
+	// Points near each root of z³-1 converge to it at once,
+	// so they have the full-brightness color of its hue.
+	seen := make(map[[3]uint8]bool)
+	for _, z := range []complex128{1.01, -0.5 + 0.866i, -0.5 - 0.866i} {
+		c := newton(z, 3)
+		seen[[3]uint8{c.R, c.G, c.B}] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("the three roots have %d colors, want 3", len(seen))
+	}
+}
+
+func TestAnimate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mandelbrot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	v, _ := parseView("-0.75,0.1", 100)
+	fr, _ := parseFractal("julia", "-0.8,0.156", 3)
+	pal := palettes["fire"]
+
+	name := filepath.Join(dir, "zoom.gif")
+	if err := animate(name, v, 4, 0.04, 3, 32, 24, fr, pal); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 3 || anim.Image[0].Bounds().Dx() != 32 {
+		t.Errorf("got %d frames of %v, want 3 of width 32", len(anim.Image), anim.Image[0].Bounds())
+	}
+
+	if err := animate(filepath.Join(dir, "f%d.png"), v, 4, 0.04, 2, 8, 8, fr, pal); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"f0.png", "f1.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
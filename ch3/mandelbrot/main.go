@@ -5,33 +5,153 @@
 //!+
 
 // Mandelbrot emits a PNG image of the Mandelbrot fractal.
+// With the -http flag, it instead serves a zoomable map of it.
 package main
 
 import (
+	"flag"
+	"fmt"
 	"image"
 	"image/color"
 	"image/png"
+	"io"
+	"math"
 	"math/cmplx"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	httpAddr = flag.String("http", "", "serve map tiles on this address, e.g. localhost:8000")
+	center   = flag.String("center", "0,0", "center of the image, as re,im")
+	zoom     = flag.Float64("zoom", 1, "magnification; the image is 4/zoom units wide")
+	size     = flag.String("size", "1024", "size of the image in pixels, as n or WxH")
+	iter     = flag.Int("iter", 200, "maximum number of iterations")
+	deep     = flag.Bool("deep", false, "use arbitrary precision, for zooms beyond about 1e10")
+	palName  = flag.String("palette", "gray", "palette name ("+paletteNames()+") or list of colors, e.g. 000764,edffff,ffaa00")
+	samples  = flag.Int("ss", 1, "supersample each pixel with ss×ss points")
+	kind     = flag.String("fractal", "mandelbrot", "the fractal: "+strings.Join(fractals, ", "))
+	juliaC   = flag.String("c", "-0.8,0.156", "the constant of a Julia set, as re,im")
+	degree   = flag.Int("degree", 3, "the degree n of z^n-1, for newton")
+	output   = flag.String("o", "", "output file (default standard output)")
+	frames   = flag.Int("frames", 0, "if positive, write an animation of this many frames zooming into the center")
+	zoomTo   = flag.Float64("zoomto", 1e6, "magnification of the last frame of an animation")
+	delay    = flag.Int("delay", 8, "delay between frames of a GIF animation, in 100ths of a second")
 )
 
 func main() {
-	const (
-		xmin, ymin, xmax, ymax = -2, -2, +2, +2
-		width, height          = 1024, 1024
-	)
+	flag.Parse()
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "mandelbrot: %v\n", err)
+		os.Exit(1)
+	}
+}
 
+func run() error {
+	if *samples < 1 {
+		return fmt.Errorf("invalid -ss %d, want at least 1", *samples)
+	}
+	pal, err := parsePalette(*palName)
+	if err != nil {
+		return err
+	}
+	fr, err := parseFractal(*kind, *juliaC, *degree)
+	if err != nil {
+		return err
+	}
+	if *deep && fr.name != "mandelbrot" {
+		return fmt.Errorf("-deep applies only to the mandelbrot fractal")
+	}
+	if *httpAddr != "" {
+		serve(*httpAddr, fr, pal)
+		return nil
+	}
+	width, height, err := parseSize(*size)
+	if err != nil {
+		return err
+	}
+	if *frames > 0 {
+		// The center must be precise enough for the last frame.
+		v, err := parseView(*center, math.Max(*zoom, *zoomTo))
+		if err != nil {
+			return err
+		}
+		return animate(*output, v, 4 / *zoom, 4 / *zoomTo, *frames, width, height, fr, pal)
+	}
+	v, err := parseView(*center, *zoom)
+	if err != nil {
+		return err
+	}
 	img := image.NewRGBA(image.Rect(0, 0, width, height))
-	for py := 0; py < height; py++ {
-		y := float64(py)/height*(ymax-ymin) + ymin
-		for px := 0; px < width; px++ {
-			x := float64(px)/width*(xmax-xmin) + xmin
-			z := complex(x, y)
-			// Image point (px, py) represents complex value z.
-			img.Set(px, py, mandelbrot(z))
+	render(img, v.width, *samples, v.colorFunc(fr, pal))
+	if *output == "" {
+		return png.Encode(os.Stdout, img)
+	}
+	return writeFile(*output, func(w io.Writer) error { return png.Encode(w, img) })
+}
+
+// parseSize parses an image size of the form n (for n×n) or WxH.
+func parseSize(s string) (width, height int, err error) {
+	parts := strings.Split(s, "x")
+	width, err1 := strconv.Atoi(parts[0])
+	height, err2 := width, error(nil)
+	if len(parts) == 2 {
+		height, err2 = strconv.Atoi(parts[1])
+	}
+	if len(parts) > 2 || err1 != nil || err2 != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid size %q, want n or WxH", s)
+	}
+	return width, height, nil
+}
+
+// render sets each pixel of img to the mean color of f(d) over
+// ss×ss points, where d is the offset from the center of the image
+// to the point, in a complex plane of the given width.  The rows are
+// shared among one goroutine per CPU, so f must be safe for
+// concurrent use.
+func render(img *image.RGBA, width float64, ss int, f func(d complex128) color.RGBA) {
+	b := img.Bounds()
+	rows := make(chan int, b.Dy())
+	for py := b.Min.Y; py < b.Max.Y; py++ {
+		rows <- py
+	}
+	close(rows)
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for py := range rows {
+				renderRow(img, py, width, ss, f)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// renderRow renders row py of img for render.
+func renderRow(img *image.RGBA, py int, width float64, ss int, f func(d complex128) color.RGBA) {
+	b := img.Bounds()
+	scale := width / float64(b.Dx()) // units per pixel
+	cx, cy := float64(b.Min.X+b.Max.X)/2, float64(b.Min.Y+b.Max.Y)/2
+	for px := b.Min.X; px < b.Max.X; px++ {
+		var r, g, bl, a int
+		for i := 0; i < ss; i++ {
+			for j := 0; j < ss; j++ {
+				x := float64(px) + (float64(i)+0.5)/float64(ss) - cx
+				y := float64(py) + (float64(j)+0.5)/float64(ss) - cy
+				// The sample at (x, y) represents offset d.
+				c := f(complex(x*scale, y*scale))
+				r, g, bl, a = r+int(c.R), g+int(c.G), bl+int(c.B), a+int(c.A)
+			}
 		}
+		n := ss * ss
+		img.SetRGBA(px, py, color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), uint8(a / n)})
 	}
-	png.Encode(os.Stdout, img) // NOTE: ignoring errors
 }
 
 func mandelbrot(z complex128) color.Color {
@@ -66,19 +186,25 @@ func sqrt(z complex128) color.Color {
 	return color.YCbCr{128, blue, red}
 }
 
-// f(x) = x^4 - 1
+// newton returns the color of z under Newton's method for
+// f(z) = z^n - 1:
+//
+//	z' = z - f(z)/f'(z)
+//	   = z - (z^n - 1) / (n * z^(n-1))
 //
-// z' = z - f(z)/f'(z)
-//    = z - (z^4 - 1) / (4 * z^3)
-//    = z - (z - 1/z^3) / 4
-func newton(z complex128) color.Color {
+// The hue shows which of the n roots z converges to, and the shade
+// how many iterations it takes.
+func newton(z complex128, n int) color.RGBA {
 	const iterations = 37
 	const contrast = 7
-	for i := uint8(0); i < iterations; i++ {
-		z -= (z - 1/(z*z*z)) / 4
-		if cmplx.Abs(z*z*z*z-1) < 1e-6 {
-			return color.Gray{255 - contrast*i}
+	for i := 0; i < iterations; i++ {
+		zn1 := cmplx.Pow(z, complex(float64(n-1), 0))
+		z -= (zn1*z - 1) / (complex(float64(n), 0) * zn1)
+		if cmplx.Abs(cmplx.Pow(z, complex(float64(n), 0))-1) < 1e-6 {
+			// The roots are e^(2πik/n), k = 0...n-1.
+			k := math.Mod(math.Round(cmplx.Phase(z)/(2*math.Pi)*float64(n))+float64(n), float64(n))
+			return hsv(k/float64(n), 1, 1-float64(contrast*i)/255)
 		}
 	}
-	return color.Black
+	return color.RGBA{0, 0, 0, 0xff}
 }
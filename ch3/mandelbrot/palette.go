@@ -0,0 +1,71 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A palette is a cycle of colors for escaping points, interpolated
+// according to the smoothed iteration count.
+type palette []color.RGBA
+
+// period is the number of iterations in one cycle of a palette.
+const period = 32
+
+var palettes = map[string]palette{
+	"gray":    {{0xff, 0xff, 0xff, 0xff}, {0x00, 0x00, 0x00, 0xff}},
+	"fire":    {{0x00, 0x00, 0x00, 0xff}, {0xc0, 0x10, 0x00, 0xff}, {0xff, 0xa0, 0x00, 0xff}, {0xff, 0xff, 0xc0, 0xff}},
+	"ocean":   {{0x00, 0x07, 0x64, 0xff}, {0x20, 0x6b, 0xcb, 0xff}, {0xed, 0xff, 0xff, 0xff}, {0xff, 0xaa, 0x00, 0xff}, {0x00, 0x02, 0x00, 0xff}},
+	"rainbow": {{0xff, 0x00, 0x00, 0xff}, {0xff, 0xff, 0x00, 0xff}, {0x00, 0xff, 0x00, 0xff}, {0x00, 0xff, 0xff, 0xff}, {0x00, 0x00, 0xff, 0xff}, {0xff, 0x00, 0xff, 0xff}},
+}
+
+func paletteNames() string {
+	var names []string
+	for name := range palettes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parsePalette returns the named palette, or the palette of a
+// comma-separated list of colors in the form rrggbb.
+func parsePalette(s string) (palette, error) {
+	if p, ok := palettes[s]; ok {
+		return p, nil
+	}
+	var p palette
+	for _, c := range strings.Split(s, ",") {
+		n, err := strconv.ParseUint(strings.TrimPrefix(c, "#"), 16, 32)
+		if err != nil || len(strings.TrimPrefix(c, "#")) != 6 {
+			return nil, fmt.Errorf("invalid palette %q: want one of %s or colors such as ff8000,000000",
+				s, paletteNames())
+		}
+		p = append(p, color.RGBA{uint8(n >> 16), uint8(n >> 8), uint8(n), 0xff})
+	}
+	if len(p) < 2 {
+		return nil, fmt.Errorf("invalid palette %q: need at least two colors", s)
+	}
+	return p, nil
+}
+
+// color returns the color for a smoothed iteration count nu,
+// or black if nu is negative (the point did not escape).
+func (p palette) color(nu float64) color.RGBA {
+	if nu < 0 {
+		return color.RGBA{0, 0, 0, 0xff}
+	}
+	t := math.Mod(nu/period, 1) * float64(len(p))
+	i := int(t)
+	a, b := p[i%len(p)], p[(i+1)%len(p)]
+	f := t - float64(i)
+	mix := func(x, y uint8) uint8 { return uint8(float64(x) + f*(float64(y)-float64(x)) + 0.5) }
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 0xff}
+}
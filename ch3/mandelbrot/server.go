@@ -0,0 +1,300 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"html/template"
+	"image"
+	"image/png"
+	"log"
+	"math"
+	"math/big"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	workers   = flag.Int("workers", runtime.NumCPU(), "number of tiles to render at once")
+	queueSize = flag.Int("queue", 256, "number of tiles that may wait to be rendered before requests are refused")
+	cacheSize = flag.Int("cache", 4096, "number of tiles to keep in memory")
+)
+
+// The map of the set is divided into square tiles, as in common
+// web maps.  At zoom level z, there are 2^z × 2^z tiles covering the
+// square [-2, 2]², numbered from the top left.
+const tileSize = 256 // pixels
+
+// maxZoom returns the deepest zoom level.  Without -deep, pixels
+// beyond level 40 are too close to distinguish in float64; with it,
+// the limit is that of the int64 tile numbers.
+func maxZoom() int {
+	if *deep {
+		return 60
+	}
+	return 40
+}
+
+type tile struct {
+	z    int
+	x, y int64
+}
+
+// view returns the region of the complex plane that t covers.
+func (t tile) view() *view {
+	// The center is -2 + (2x+1)·2^(1-z), computed exactly.
+	v := &view{width: math.Ldexp(4, -t.z)}
+	prec := precision(v.width)
+	coord := func(i int64) *big.Float {
+		f := new(big.Float).SetPrec(prec).SetInt64(2*i + 1)
+		f.SetMantExp(f, 1-t.z)
+		return f.Sub(f, big.NewFloat(2))
+	}
+	v.cx, v.cy = coord(t.x), coord(t.y)
+	return v
+}
+
+// parseTile parses a path of the form /z/x/y.png.
+func parseTile(path string) (tile, bool) {
+	if !strings.HasPrefix(path, "/") || !strings.HasSuffix(path, ".png") {
+		return tile{}, false
+	}
+	parts := strings.Split(strings.TrimSuffix(path[1:], ".png"), "/")
+	if len(parts) != 3 {
+		return tile{}, false
+	}
+	var n [3]int64
+	for i, part := range parts {
+		v, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || v < 0 {
+			return tile{}, false
+		}
+		n[i] = v
+	}
+	if n[0] > int64(maxZoom()) || n[1]>>uint(n[0]) > 0 || n[2]>>uint(n[0]) > 0 {
+		return tile{}, false
+	}
+	return tile{int(n[0]), n[1], n[2]}, true
+}
+
+// An entry is a cached tile, which may still be being rendered.
+type entry struct {
+	png     []byte        // the encoded image
+	ready   chan struct{} // closed when png is ready
+	waiters int           // number of requests waiting for png; guarded by tileServer.mu
+}
+
+// errBusy is returned by get when too many tiles are waiting.
+var errBusy = errors.New("too many tiles waiting to be rendered")
+
+// A tileServer renders tiles using a fixed number of worker
+// goroutines and caches the most recently used ones.  Concurrent
+// requests for the same tile wait for a single rendering.  At most
+// queueSize tiles wait for a worker; beyond that, requests fail.
+// A tile whose requests have all gone away is not rendered.
+type tileServer struct {
+	jobs chan *job
+	fr   fractal
+	pal  palette
+
+	mu    sync.Mutex // guards cache and the waiters of its entries
+	cache *lru
+}
+
+type job struct {
+	t tile
+	e *entry
+}
+
+func newTileServer(workers, queueSize, cacheSize int, fr fractal, pal palette) *tileServer {
+	s := &tileServer{
+		jobs:  make(chan *job, queueSize),
+		fr:    fr,
+		pal:   pal,
+		cache: newLRU(cacheSize),
+	}
+	for i := 0; i < workers; i++ {
+		go s.worker()
+	}
+	return s
+}
+
+func (s *tileServer) worker() {
+	for j := range s.jobs {
+		s.mu.Lock()
+		abandoned := j.e.waiters == 0
+		if abandoned {
+			s.cache.remove(j.t, j.e) // a later request starts afresh
+		}
+		s.mu.Unlock()
+		if abandoned {
+			continue
+		}
+
+		img := image.NewRGBA(image.Rect(0, 0, tileSize, tileSize))
+		v := j.t.view()
+		render(img, v.width, *samples, v.colorFunc(s.fr, s.pal))
+		var buf bytes.Buffer
+		png.Encode(&buf, img) // NOTE: ignoring errors
+		j.e.png = buf.Bytes()
+		close(j.e.ready)
+	}
+}
+
+// get returns the PNG encoding of tile t.  It returns early if ctx is
+// cancelled; the tile is still rendered and cached if other requests
+// are waiting for it.  It returns errBusy if the queue is full.
+func (s *tileServer) get(ctx context.Context, t tile) ([]byte, error) {
+	s.mu.Lock()
+	e, ok := s.cache.get(t)
+	if !ok {
+		e = &entry{ready: make(chan struct{})}
+		select {
+		case s.jobs <- &job{t, e}:
+			s.cache.add(t, e)
+		default:
+			s.mu.Unlock()
+			return nil, errBusy
+		}
+	}
+	e.waiters++
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		e.waiters--
+		s.mu.Unlock()
+	}()
+
+	select {
+	case <-e.ready:
+		return e.png, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *tileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" {
+		viewer.Execute(w, maxZoom())
+		return
+	}
+	t, ok := parseTile(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := s.get(r.Context(), t)
+	if err == errBusy {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		return // the client went away
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(data)
+}
+
+func serve(addr string, fr fractal, pal palette) {
+	log.Printf("serving on http://%s/", addr)
+	log.Fatal(http.ListenAndServe(addr, newTileServer(*workers, *queueSize, *cacheSize, fr, pal)))
+}
+
+// viewer is a page that displays the tiles as a map that can be
+// dragged with the mouse and zoomed with the wheel.
+var viewer = template.Must(template.New("viewer").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Mandelbrot</title>
+<style>
+body { margin: 0; overflow: hidden; }
+#map { position: absolute; width: 100%; height: 100%; background: black; cursor: move; }
+#map img { position: absolute; width: 256px; height: 256px; }
+#zoom { position: absolute; left: 8px; top: 8px; color: white; font: 12px sans-serif; }
+</style>
+</head>
+<body>
+<div id="map"></div>
+<div id="zoom"></div>
+<script>
+var maxZoom = {{.}};
+var tileSize = 256;
+var map = document.getElementById("map");
+// The center, in pixels from the top left of the whole map at the
+// current zoom.  Deep zooms need more than the 53 bits of a Number.
+var zoom = 2, cx = 512n, cy = 512n;
+var tiles = {}; // the img elements, by path
+
+function draw() {
+	var n = 1n << BigInt(zoom), size = BigInt(tileSize);
+	var w = map.clientWidth, h = map.clientHeight;
+	var left = cx - BigInt(Math.round(w/2)), top = cy - BigInt(Math.round(h/2));
+	var floor = function(a) { return a < 0n ? -((-a + size - 1n) / size) : a / size; };
+	var visible = {};
+	for (var y = floor(top); y < n && (y*size - top) < BigInt(h); y++) {
+		if (y < 0n) continue;
+		for (var x = floor(left); x < n && (x*size - left) < BigInt(w); x++) {
+			if (x < 0n) continue;
+			var path = "/" + zoom + "/" + x + "/" + y + ".png";
+			var img = tiles[path];
+			if (!img) {
+				img = tiles[path] = document.createElement("img");
+				img.src = path;
+				img.draggable = false;
+				map.appendChild(img);
+			}
+			img.style.left = Number(x*size - left) + "px";
+			img.style.top = Number(y*size - top) + "px";
+			visible[path] = true;
+		}
+	}
+	for (var path in tiles) {
+		if (!visible[path]) {
+			map.removeChild(tiles[path]);
+			delete tiles[path];
+		}
+	}
+	document.getElementById("zoom").textContent = "zoom " + zoom;
+}
+
+var dragging = null;
+map.onmousedown = function(e) { dragging = {x: e.clientX, y: e.clientY}; };
+window.onmouseup = function() { dragging = null; };
+window.onmousemove = function(e) {
+	if (!dragging) return;
+	cx -= BigInt(Math.round(e.clientX - dragging.x));
+	cy -= BigInt(Math.round(e.clientY - dragging.y));
+	dragging = {x: e.clientX, y: e.clientY};
+	draw();
+};
+map.onwheel = function(e) {
+	e.preventDefault();
+	// Keep the point under the mouse where it is.
+	var dx = BigInt(Math.round(e.clientX - map.clientWidth/2));
+	var dy = BigInt(Math.round(e.clientY - map.clientHeight/2));
+	if (e.deltaY < 0 && zoom < maxZoom) {
+		zoom++;
+		cx = 2n*cx + dx;
+		cy = 2n*cy + dy;
+	} else if (e.deltaY > 0 && zoom > 0) {
+		zoom--;
+		cx = (cx - dx) / 2n;
+		cy = (cy - dy) / 2n;
+	}
+	draw();
+};
+window.onresize = draw;
+draw();
+</script>
+</body>
+</html>
+`))
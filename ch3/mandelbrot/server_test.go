@@ -0,0 +1,116 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseTile(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		want tile
+		ok   bool
+	}{
+		{"/0/0/0.png", tile{0, 0, 0}, true},
+		{"/3/7/2.png", tile{3, 7, 2}, true},
+		{"/3/8/2.png", tile{}, false}, // x out of range
+		{"/41/0/0.png", tile{}, false},
+		{"/1/0/-1.png", tile{}, false},
+		{"/1/0/0.gif", tile{}, false},
+		{"/1/0.png", tile{}, false},
+	} {
+		got, ok := parseTile(test.path)
+		if got != test.want || ok != test.ok {
+			t.Errorf("parseTile(%q) = %v, %t, want %v, %t", test.path, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestServeTile(t *testing.T) {
+	s := newTileServer(2, 10, 10, fractal{name: "mandelbrot"}, palettes["gray"])
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// Concurrent requests for the same tile share one rendering.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(ts.URL + "/2/1/1.png")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer resp.Body.Close()
+			if ct := resp.Header.Get("Content-Type"); ct != "image/png" {
+				t.Errorf("Content-Type = %s", ct)
+			}
+			img, err := png.Decode(resp.Body)
+			if err != nil {
+				t.Error(err)
+			} else if b := img.Bounds(); b.Dx() != tileSize || b.Dy() != tileSize {
+				t.Errorf("tile size = %v", b)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := s.cache.len(); n != 1 {
+		t.Errorf("cache has %d entries, want 1", n)
+	}
+
+	resp, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	buf.ReadFrom(resp.Body)
+	resp.Body.Close()
+	if !bytes.Contains(buf.Bytes(), []byte("var maxZoom =")) {
+		t.Errorf("viewer page lacks script:\n%s", &buf)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	// With no workers yet, the first tile fills the queue.
+	s := newTileServer(0, 1, 10, fractal{name: "mandelbrot"}, palettes["gray"])
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.get(ctx, tile{1, 0, 0}); err != context.Canceled {
+		t.Fatalf("get with cancelled context: %v", err)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/1/1/0.png", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("request with full queue: status %d, want 503", w.Code)
+	}
+
+	// The abandoned tile is skipped, not rendered and cached.
+	go s.worker()
+	for {
+		_, err := s.get(context.Background(), tile{1, 1, 0})
+		if err == nil {
+			break
+		}
+		if err != errBusy {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.mu.Lock()
+	_, ok := s.cache.get(tile{1, 0, 0})
+	n := s.cache.len()
+	s.mu.Unlock()
+	if ok || n != 1 {
+		t.Errorf("abandoned tile cached: %t; cache has %d entries, want 1", ok, n)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// A view is a square region of the complex plane.  Its center is
+// held with enough precision to distinguish the pixels of a deep zoom.
+type view struct {
+	cx, cy *big.Float
+	width  float64
+}
+
+// precision returns the number of bits needed for the center of a
+// view of the given width, with a margin for rounding errors.
+func precision(width float64) uint {
+	bits := 64
+	if width < 1 {
+		bits += int(math.Ceil(-math.Log2(width)))
+	}
+	return uint(bits)
+}
+
+// parseView returns the view centered at re,im that is 4/zoom units wide.
+func parseView(center string, zoom float64) (*view, error) {
+	if !(zoom > 0) || math.IsInf(zoom, 0) {
+		return nil, fmt.Errorf("invalid zoom %g", zoom)
+	}
+	v := &view{width: 4 / zoom}
+	parts := strings.Split(center, ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid center %q, want re,im", center)
+	}
+	prec := precision(v.width)
+	var err error
+	if v.cx, _, err = big.ParseFloat(strings.TrimSpace(parts[0]), 10, prec, big.ToNearestEven); err != nil {
+		return nil, fmt.Errorf("invalid center %q: %v", center, err)
+	}
+	if v.cy, _, err = big.ParseFloat(strings.TrimSpace(parts[1]), 10, prec, big.ToNearestEven); err != nil {
+		return nil, fmt.Errorf("invalid center %q: %v", center, err)
+	}
+	return v, nil
+}
+
+// colorFunc returns a function giving the color in fractal fr of
+// the point at offset d from the center of v.  Only the Mandelbrot
+// set may be computed with -deep.
+func (v *view) colorFunc(fr fractal, pal palette) func(d complex128) color.RGBA {
+	n := *iter
+	if *deep {
+		z := orbit(v.cx, v.cy, n)
+		return func(d complex128) color.RGBA { return pal.color(perturbed(z, d, n)) }
+	}
+	x, _ := v.cx.Float64()
+	y, _ := v.cy.Float64()
+	c0 := complex(x, y)
+	switch fr.name {
+	case "julia":
+		return func(d complex128) color.RGBA { return pal.color(julia(c0+d, fr.c, n)) }
+	case "ship":
+		return func(d complex128) color.RGBA { return pal.color(ship(c0+d, n)) }
+	case "newton":
+		return func(d complex128) color.RGBA { return newton(c0+d, fr.degree) }
+	case "acos":
+		return func(d complex128) color.RGBA { return toRGBA(acos(c0 + d)) }
+	case "sqrt":
+		return func(d complex128) color.RGBA { return toRGBA(sqrt(c0 + d)) }
+	}
+	return func(d complex128) color.RGBA { return pal.color(escape(c0+d, n)) }
+}
+
+// The escape radius is large so that the smoothed iteration count
+// is continuous.
+const bailout = 256
+
+// escape returns the smoothed number of iterations of z = z² + c
+// before z escapes, or -1 if it does not escape within n iterations.
+func escape(c complex128, n int) float64 {
+	var z complex128
+	for i := 0; i < n; i++ {
+		z = z*z + c
+		if r2 := real(z)*real(z) + imag(z)*imag(z); r2 > bailout*bailout {
+			return smooth(i, r2)
+		}
+	}
+	return -1
+}
+
+// smooth returns the normalized iteration count of a point that
+// escaped after i iterations with |z|² = r2.  It increases
+// continuously with the point, removing the bands of a plain count.
+func smooth(i int, r2 float64) float64 {
+	return float64(i) + 1 - math.Log2(math.Log(r2)/2/math.Log(bailout))
+}
+
+// ---- perturbation ----
+
+// Beyond a zoom of about 1e13, neighbouring pixels are no longer
+// distinct in float64.  Instead we compute the orbit Z of the center
+// C with math/big, and for each point C+d the difference z-Z, which
+// is small but, unlike z, can be represented in float64:
+//
+//	(z-Z)' = 2Z(z-Z) + (z-Z)² + d
+//
+// When the difference grows larger than z itself, or Z escapes, we
+// continue from the start of the orbit with z-0 ("rebasing"), which
+// avoids the glitches of naive perturbation.
+
+// orbit returns the orbit 0, C, C²+C, ... of the point C = cx+cy·i,
+// computed with the precision of cx, and rounded to complex128.
+// It ends after n iterations or when the point escapes.
+func orbit(cx, cy *big.Float, n int) []complex128 {
+	prec := cx.Prec()
+	newFloat := func() *big.Float { return new(big.Float).SetPrec(prec) }
+	x, y := newFloat(), newFloat()
+	x2, y2, xy := newFloat(), newFloat(), newFloat()
+	z := []complex128{0}
+	for i := 0; i < n; i++ {
+		x2.Mul(x, x)
+		y2.Mul(y, y)
+		xy.Mul(x, y)
+		x.Sub(x2, y2).Add(x, cx)
+		y.Add(xy, xy).Add(y, cy)
+		fx, _ := x.Float64()
+		fy, _ := y.Float64()
+		z = append(z, complex(fx, fy))
+		if fx*fx+fy*fy > bailout*bailout {
+			break
+		}
+	}
+	return z
+}
+
+// perturbed is like escape for the point at offset d from the
+// center of the reference orbit z.
+func perturbed(z []complex128, d complex128, n int) float64 {
+	var dz complex128 // z-Z
+	m := 0            // index in the reference orbit
+	for i := 0; i < n; i++ {
+		dz = 2*z[m]*dz + dz*dz + d
+		m++
+		w := z[m] + dz // the point's own z
+		r2 := real(w)*real(w) + imag(w)*imag(w)
+		if r2 > bailout*bailout {
+			return smooth(i, r2)
+		}
+		if r2 < real(dz)*real(dz)+imag(dz)*imag(dz) || m == len(z)-1 {
+			dz, m = w, 0 // rebase
+		}
+	}
+	return -1
+}
@@ -0,0 +1,67 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+// TestPerturbed checks that perturbation agrees with direct
+// iteration at a zoom where float64 is still accurate.
+func TestPerturbed(t *testing.T) {
+	v, err := parseView("-0.75,0.1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 500
+	z := orbit(v.cx, v.cy, n)
+	for _, d := range []complex128{0, 0.01, -0.02i, 0.1 + 0.1i, -0.15 + 0.05i} {
+		want := escape(complex(-0.75, 0.1)+d, n)
+		got := perturbed(z, d, n)
+		if (want < 0) != (got < 0) || math.Abs(got-want) > 1e-6 {
+			t.Errorf("perturbed(%v) = %g, escape = %g", d, got, want)
+		}
+	}
+}
+
+func TestTileView(t *testing.T) {
+	v := tile{z: 2, x: 1, y: 3}.view()
+	if x, _ := v.cx.Float64(); x != -0.5 {
+		t.Errorf("cx = %g, want -0.5", x)
+	}
+	if y, _ := v.cy.Float64(); y != 1.5 {
+		t.Errorf("cy = %g, want 1.5", y)
+	}
+	if v.width != 1 {
+		t.Errorf("width = %g, want 1", v.width)
+	}
+
+	// At zoom 60, the center must not be rounded.
+	v = tile{z: 60, x: 1, y: 0}.view()
+	want := new(big.Float).SetMantExp(big.NewFloat(3), -59)
+	want.SetPrec(200).Sub(want, big.NewFloat(2))
+	if v.cx.Cmp(want) != 0 {
+		t.Errorf("cx = %s, want %s", v.cx.Text('g', 30), want.Text('g', 30))
+	}
+}
+
+func TestPalette(t *testing.T) {
+	p, err := parsePalette("000000,#ffffff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := p.color(period / 4); c.R != 0x80 {
+		t.Errorf("color at quarter period = %v, want mid-gray", c)
+	}
+	if c := p.color(-1); c.R != 0 || c.A != 0xff {
+		t.Errorf("color of interior = %v, want black", c)
+	}
+	for _, bad := range []string{"purple", "ff0000", "ff0000,12345"} {
+		if _, err := parsePalette(bad); err == nil {
+			t.Errorf("parsePalette(%q) succeeded", bad)
+		}
+	}
+}
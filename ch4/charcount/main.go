@@ -4,54 +4,177 @@
 // See page 97.
 //!+
 
-// Charcount computes counts of Unicode characters.
+// Charcount computes counts of Unicode characters, and statistics
+// such as their categories and scripts, of the standard input or of
+// the named files, which are read in parallel.
 // 调用示例: $ echo '123abcd定的出想啊牌你说的发掘a' | go run main.go
 package main
 
 import (
-	"bufio"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"os"
-	"unicode"
-	"unicode/utf8"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	top     = flag.Int("top", 20, "print only the n most frequent runes, categories and scripts (0 means all)")
+	byName  = flag.Bool("byname", false, "sort by rune or name instead of by count")
+	jsonOut = flag.Bool("json", false, "print the statistics of each file, and the total, as JSON")
 )
 
 func main() {
-	counts := make(map[rune]int)    // counts of Unicode characters
-	// utf8.UTFMax: UTF-8编码的unicode字符最大有多少个字节
-	var utflen [utf8.UTFMax + 1]int // count of lengths of UTF-8 encodings
-	invalid := 0                    // count of invalid UTF-8 characters
-
-	in := bufio.NewReader(os.Stdin)
-	for {
-		r, n, err := in.ReadRune() // returns rune, nbytes, error
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
+	flag.Parse()
+	files := flag.Args()
+	var results []*stats
+	if len(files) == 0 {
+		s := newStats("")
+		if err := s.count(os.Stdin); err != nil {
 			fmt.Fprintf(os.Stderr, "charcount: %v\n", err)
 			os.Exit(1)
 		}
-		if r == unicode.ReplacementChar && n == 1 {
-			invalid++
-			continue
+		results = append(results, s)
+	} else {
+		results = countFiles(files)
+	}
+
+	total := newStats("")
+	status := 0
+	for _, s := range results {
+		if s.Err != "" {
+			fmt.Fprintf(os.Stderr, "charcount: %s\n", s.Err)
+			status = 1
+		}
+		total.add(s)
+	}
+	if *jsonOut {
+		out := struct {
+			Files []*stats `json:"files,omitempty"`
+			Total *stats   `json:"total"`
+		}{Total: total}
+		if len(files) > 0 {
+			out.Files = results
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(out)
+	} else {
+		if len(results) > 1 {
+			for _, s := range results {
+				fmt.Printf("%s:\n", s.Name)
+				printFile(os.Stdout, s)
+			}
+			fmt.Println()
+		} else if len(results) == 1 {
+			printFile(os.Stdout, results[0])
 		}
-		counts[r]++
-		utflen[n]++
+		printCounts(os.Stdout, total)
+	}
+	os.Exit(status)
+}
+
+// countFiles computes the statistics of the named files, using one
+// goroutine per CPU.
+func countFiles(files []string) []*stats {
+	results := make([]*stats, len(files))
+	sema := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for i, name := range files {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sema <- struct{}{} // acquire token
+			defer func() { <-sema }()
+			s := newStats(name)
+			if err := countFile(s, name); err != nil {
+				s.Err = err.Error()
+			}
+			results[i] = s
+		}(i, name)
 	}
-	fmt.Printf("rune\tcount\n")
-	for c, n := range counts {
-		fmt.Printf("%q\t%d\n", c, n)
+	wg.Wait()
+	return results
+}
+
+func countFile(s *stats, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
 	}
-	fmt.Print("\nlen\tcount\n")
-	for i, n := range utflen {
+	defer f.Close()
+	return s.count(f)
+}
+
+// printFile prints the properties of the encoding of s.
+func printFile(w io.Writer, s *stats) {
+	fmt.Fprintf(w, "%d bytes, %d runes, %d clusters, %d words, %d lines\n",
+		s.Bytes, s.Runes, s.Clusters, s.Words, s.Lines)
+	if s.BOM != "" {
+		fmt.Fprintf(w, "%s byte order mark\n", s.BOM)
+	}
+	if s.mixedLineEndings() {
+		var kinds []string
+		for _, kind := range []string{"LF", "CRLF", "CR"} {
+			if n := s.LineEndings[kind]; n > 0 {
+				kinds = append(kinds, fmt.Sprintf("%d %s", n, kind))
+			}
+		}
+		fmt.Fprintf(w, "mixed line endings: %s\n", strings.Join(kinds, ", "))
+	}
+	if s.Invalid > 0 {
+		var offsets []string
+		for _, off := range s.Offsets {
+			offsets = append(offsets, fmt.Sprint(off))
+		}
+		if s.Invalid > len(s.Offsets) {
+			offsets = append(offsets, "...")
+		}
+		fmt.Fprintf(w, "%d invalid UTF-8 bytes, at offsets %s\n", s.Invalid, strings.Join(offsets, " "))
+	}
+}
+
+// printCounts prints the tables of counts of s.
+func printCounts(w io.Writer, s *stats) {
+	printTable(w, "rune", s.Chars, func(k string) string { return fmt.Sprintf("%q", k) })
+	printTable(w, "category", s.Categories, nil)
+	printTable(w, "script", s.Scripts, nil)
+	fmt.Fprint(w, "\nlen\tcount\n")
+	for i, n := range s.UTFLen {
 		if i > 0 {
-			fmt.Printf("%d\t%d\n", i, n)
+			fmt.Fprintf(w, "%d\t%d\n", i, n)
 		}
 	}
-	if invalid > 0 {
-		fmt.Printf("\n%d invalid UTF-8 characters\n", invalid)
+}
+
+// printTable prints the counts, sorted and limited as specified by
+// the flags.  If format is non-nil, it formats the keys.
+func printTable(w io.Writer, heading string, counts map[string]int, format func(string) string) {
+	var keys []string
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		x, y := keys[i], keys[j]
+		if !*byName && counts[x] != counts[y] {
+			return counts[x] > counts[y]
+		}
+		return x < y
+	})
+	fmt.Fprintf(w, "\n%s\tcount\n", heading)
+	for i, k := range keys {
+		if *top > 0 && i == *top {
+			fmt.Fprintf(w, "(%d more)\n", len(keys)-i)
+			break
+		}
+		if format != nil {
+			k = format(k)
+		}
+		fmt.Fprintf(w, "%s\t%d\n", k, counts[keys[i]])
 	}
 }
 
@@ -0,0 +1,221 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxOffsets is the number of offsets of invalid bytes recorded per file.
+const maxOffsets = 20
+
+// stats holds the statistics of a text.
+type stats struct {
+	Name     string `json:"name,omitempty"`
+	Bytes    int64  `json:"bytes"`
+	Runes    int    `json:"runes"`
+	Lines    int    `json:"lines"`
+	Words    int    `json:"words"`    // sequences of non-space runes
+	Clusters int    `json:"clusters"` // a base rune and its combining marks
+
+	Chars      map[string]int       `json:"chars"`        // counts of each rune
+	Categories map[string]int       `json:"categories"`   // counts by general category, e.g. Lu
+	Scripts    map[string]int       `json:"scripts"`      // counts by script, e.g. Latin
+	UTFLen     [utf8.UTFMax + 1]int `json:"utf8_lengths"` // counts of lengths of UTF-8 encodings
+
+	BOM         string         `json:"bom,omitempty"`             // byte order mark, e.g. UTF-8
+	LineEndings map[string]int `json:"line_endings,omitempty"`    // counts of LF, CRLF and CR
+	Invalid     int            `json:"invalid"`                   // count of invalid UTF-8 bytes
+	Offsets     []int64        `json:"invalid_offsets,omitempty"` // of the first few invalid bytes
+	Err         string         `json:"error,omitempty"`
+}
+
+func newStats(name string) *stats {
+	return &stats{
+		Name:        name,
+		Chars:       make(map[string]int),
+		Categories:  make(map[string]int),
+		Scripts:     make(map[string]int),
+		LineEndings: make(map[string]int),
+	}
+}
+
+// boms are the byte order marks, by encoding.
+var boms = []struct {
+	name string
+	mark []byte
+}{
+	{"UTF-8", []byte{0xEF, 0xBB, 0xBF}},
+	{"UTF-16LE", []byte{0xFF, 0xFE}},
+	{"UTF-16BE", []byte{0xFE, 0xFF}},
+}
+
+// count reads the text from r and adds its statistics to s.
+// A UTF-8 byte order mark is not counted as a rune.
+func (s *stats) count(r io.Reader) error {
+	in := bufio.NewReader(r)
+	if start, err := in.Peek(3); err == nil || err == io.EOF {
+		for _, bom := range boms {
+			if bytes.HasPrefix(start, bom.mark) {
+				s.BOM = bom.name
+				if bom.name == "UTF-8" {
+					in.Discard(len(bom.mark))
+					s.Bytes += int64(len(bom.mark))
+				}
+				break
+			}
+		}
+	}
+
+	c := newClassifier()
+	inWord, inLine, cr := false, false, false
+	for {
+		r, n, err := in.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if r == unicode.ReplacementChar && n == 1 {
+			if len(s.Offsets) < maxOffsets {
+				s.Offsets = append(s.Offsets, s.Bytes)
+			}
+			s.Bytes++
+			s.Invalid++
+			continue
+		}
+		s.Bytes += int64(n)
+		s.Runes++
+		s.Chars[string(r)]++
+		s.UTFLen[n]++
+		cat, script := c.classify(r)
+		s.Categories[cat]++
+		if script != "" {
+			s.Scripts[script]++
+		}
+
+		// A combining mark extends the current cluster, as does
+		// LF after CR.
+		if !(unicode.Is(unicode.M, r) && s.Clusters > 0) && !(cr && r == '\n') {
+			s.Clusters++
+		}
+		if unicode.IsSpace(r) {
+			inWord = false
+		} else if !inWord {
+			inWord = true
+			s.Words++
+		}
+		if cr {
+			if r == '\n' {
+				s.LineEndings["CRLF"]++
+			} else {
+				s.LineEndings["CR"]++
+			}
+		} else if r == '\n' {
+			s.LineEndings["LF"]++
+		}
+		if r == '\r' || r == '\n' && !cr {
+			s.Lines++
+		}
+		cr = r == '\r'
+		inLine = r != '\n' && r != '\r'
+	}
+	if cr {
+		s.LineEndings["CR"]++
+	}
+	if inLine {
+		s.Lines++ // an unterminated last line
+	}
+	return nil
+}
+
+// add adds the statistics of t, except its name and offsets, to s.
+func (s *stats) add(t *stats) {
+	s.Bytes += t.Bytes
+	s.Runes += t.Runes
+	s.Lines += t.Lines
+	s.Words += t.Words
+	s.Clusters += t.Clusters
+	s.Invalid += t.Invalid
+	for _, m := range []struct{ dst, src map[string]int }{
+		{s.Chars, t.Chars},
+		{s.Categories, t.Categories},
+		{s.Scripts, t.Scripts},
+		{s.LineEndings, t.LineEndings},
+	} {
+		for k, n := range m.src {
+			m.dst[k] += n
+		}
+	}
+	for i, n := range t.UTFLen {
+		s.UTFLen[i] += n
+	}
+}
+
+// mixedLineEndings reports whether s has more than one kind of line ending.
+func (s *stats) mixedLineEndings() bool {
+	kinds := 0
+	for _, n := range s.LineEndings {
+		if n > 0 {
+			kinds++
+		}
+	}
+	return kinds > 1
+}
+
+// categories and scripts are the names of the tables in
+// unicode.Categories and unicode.Scripts, sorted.  Only the
+// two-letter categories, such as Lu, are used, not L, and not LC,
+// which is the union of Lu, Ll and Lt.
+var categories, scripts = tableNames(unicode.Categories, 2), tableNames(unicode.Scripts, 0)
+
+func tableNames(tables map[string]*unicode.RangeTable, length int) []string {
+	var names []string
+	for name := range tables {
+		if (length == 0 || len(name) == length) && name != "LC" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// A classifier finds the category and script of runes.  Searching
+// the tables is slow, so it remembers the answers.
+type classifier struct {
+	cache map[rune][2]string
+}
+
+func newClassifier() *classifier {
+	return &classifier{cache: make(map[rune][2]string)}
+}
+
+// classify returns the general category and script of r.  The script
+// of an unassigned rune is "".
+func (c *classifier) classify(r rune) (category, script string) {
+	if v, ok := c.cache[r]; ok {
+		return v[0], v[1]
+	}
+	category = "Cn" // unassigned
+	for _, name := range categories {
+		if unicode.In(r, unicode.Categories[name]) {
+			category = name
+			break
+		}
+	}
+	for _, name := range scripts {
+		if unicode.In(r, unicode.Scripts[name]) {
+			script = name
+			break
+		}
+	}
+	c.cache[r] = [2]string{category, script}
+	return category, script
+}
@@ -0,0 +1,93 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func count(t *testing.T, text string) *stats {
+	s := newStats("")
+	if err := s.count(strings.NewReader(text)); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCount(t *testing.T) {
+	// e + combining acute, CRLF, Greek, CJK, an invalid byte, CR, LF.
+	s := count(t, "\uFEFFcafe\u0301 ok\r\nαβγ 世界\xff!\rx\n")
+	for _, test := range []struct {
+		what      string
+		got, want interface{}
+	}{
+		{"BOM", s.BOM, "UTF-8"},
+		{"bytes", s.Bytes, int64(32)},
+		{"runes", s.Runes, 20},
+		{"clusters", s.Clusters, 18},
+		{"words", s.Words, 5},
+		{"lines", s.Lines, 3},
+		{"invalid", s.Invalid, 1},
+		{"offsets", s.Offsets, []int64{27}},
+		{"line endings", s.LineEndings, map[string]int{"CRLF": 1, "CR": 1, "LF": 1}},
+		{"mixed", s.mixedLineEndings(), true},
+		{"categories", s.Categories, map[string]int{
+			"Ll": 10, "Lo": 2, "Mn": 1, "Zs": 2, "Po": 1, "Cc": 4}},
+		{"scripts", s.Scripts, map[string]int{
+			"Latin": 7, "Greek": 3, "Han": 2, "Inherited": 1, "Common": 7}},
+		{"UTF-8 lengths", s.UTFLen, [5]int{0, 14, 4, 2, 0}},
+		{"chars", s.Chars["a"], 1},
+	} {
+		if !reflect.DeepEqual(test.got, test.want) {
+			t.Errorf("%s = %v, want %v", test.what, test.got, test.want)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	for _, test := range []struct {
+		text  string
+		lines int
+		mixed bool
+	}{
+		{"", 0, false},
+		{"a", 1, false},
+		{"a\nb\n", 2, false},
+		{"a\nb", 2, false},
+		{"a\r\nb\r\n", 2, false},
+		{"a\rb\r", 2, false},
+		{"a\r\nb\n", 2, true},
+		{"\n\n\r", 3, true},
+	} {
+		s := count(t, test.text)
+		if s.Lines != test.lines || s.mixedLineEndings() != test.mixed {
+			t.Errorf("%q: %d lines, mixed=%t; want %d, %t",
+				test.text, s.Lines, s.mixedLineEndings(), test.lines, test.mixed)
+		}
+	}
+}
+
+func TestBOM(t *testing.T) {
+	for text, want := range map[string]string{
+		"\xEF\xBB\xBFx": "UTF-8",
+		"\xFF\xFEx\x00": "UTF-16LE",
+		"\xFE\xFF\x00x": "UTF-16BE",
+		"x":             "",
+	} {
+		if got := count(t, text).BOM; got != want {
+			t.Errorf("BOM of %q = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	total := newStats("")
+	total.add(count(t, "ab\n"))
+	total.add(count(t, "b\r\n"))
+	if total.Chars["b"] != 2 || total.Lines != 2 || !total.mixedLineEndings() {
+		t.Errorf("total = %+v", total)
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A download fetches a URL into a local file.  The data is written
+// to local+".part" and renamed to local once complete, so local is
+// never partially written.  The validators of each file (its ETag
+// and Last-Modified headers) are kept in name+".meta", and are used
+// to resume the partial file with a Range request and to fetch the
+// complete one again only if it has changed.
+type download struct {
+	client   *http.Client
+	url      string
+	local    string
+	sha256   []byte    // expected digest, if non-nil
+	retries  int       // number of times to resume after an interruption
+	progress io.Writer // if non-nil, receives a progress line
+}
+
+// run performs the download.  It returns the size of the local file
+// and whether it was modified.
+func (d *download) run() (n int64, modified bool, err error) {
+	for attempt := 0; ; attempt++ {
+		n, modified, err = d.try()
+		if _, ok := err.(interrupted); !ok || attempt == d.retries {
+			return n, modified, err
+		}
+		if d.progress != nil {
+			fmt.Fprintf(d.progress, "%v; resuming\n", err)
+		}
+	}
+}
+
+// An interrupted error is a failure of the network during a
+// transfer, after which the download may be resumed.
+type interrupted struct{ err error }
+
+func (e interrupted) Error() string { return e.err.Error() }
+
+// try makes one request, resuming the partial file if there is one.
+func (d *download) try() (n int64, modified bool, err error) {
+	part := d.local + ".part"
+	req, err := http.NewRequest("GET", d.url, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	var offset int64
+	if info, err := os.Stat(part); err == nil && info.Size() > 0 {
+		if m := readMeta(part); m.validator() != "" {
+			offset = info.Size()
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			req.Header.Set("If-Range", m.validator())
+		}
+	} else if info, err := os.Stat(d.local); err == nil {
+		m := readMeta(d.local)
+		if m.ETag != "" {
+			req.Header.Set("If-None-Match", m.ETag)
+		}
+		if m.LastModified != "" {
+			req.Header.Set("If-Modified-Since", m.LastModified)
+		}
+		n = info.Size()
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, false, interrupted{err}
+	}
+	defer resp.Body.Close()
+
+	var f *os.File
+	total := int64(-1) // unknown
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return n, false, d.verify(d.local)
+	case http.StatusPartialContent:
+		start, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return 0, false, err
+		}
+		if start != offset {
+			return 0, false, fmt.Errorf("server resumed at byte %d, want %d", start, offset)
+		}
+		total = size
+		f, err = os.OpenFile(part, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return 0, false, err
+		}
+	case http.StatusOK:
+		// A new download, or the partial file is out of date.
+		offset = 0
+		total = resp.ContentLength
+		m := meta{resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")}
+		if err := m.write(part); err != nil {
+			return 0, false, err
+		}
+		f, err = os.Create(part)
+		if err != nil {
+			return 0, false, err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		os.Remove(part) // start again next time
+		return 0, false, fmt.Errorf("%s", resp.Status)
+	default:
+		return 0, false, fmt.Errorf("%s", resp.Status)
+	}
+
+	p := &progress{out: d.progress, done: offset, total: total, start: time.Now()}
+	n, err = copyBody(f, resp.Body, p)
+	p.finish()
+	/**
+	It's tempting to use a second deferred call, to f.Close, to close the local file, but this would be subtly wrong because
+	os.Create opens a file for writing, creating it as needed(os.Create是按需创建文件). On many file systems, notably(adv. 显著地；尤其) NFS,
+	write errors are not reported immediately but may be postponed until the file is closed. Failure
+	to check the result of the close operation could cause serious data loss to go unnoticed(go unnoticed:不被差觉的).
+	However, if both copyBody and f.Close fail, we should prefer to report the error from
+	copyBody since it occurred first and is more likely to tell us the root cause.
+	*/
+	// Close file, but prefer error from copyBody, if any.
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if err := d.verify(part); err != nil {
+		os.Remove(part) // don't resume a corrupt file
+		return 0, false, err
+	}
+	// Rename the data first: if we stop between the two, the old
+	// validators will no longer match, and local will be fetched again.
+	if err := os.Rename(part, d.local); err != nil {
+		return 0, false, err
+	}
+	if err := os.Rename(part+".meta", d.local+".meta"); err != nil {
+		return 0, false, err
+	}
+	return offset + n, true, nil
+}
+
+// copyBody copies body to f, reporting progress to p.  Errors reading
+// the body are interrupted errors.
+func copyBody(f *os.File, body io.Reader, p *progress) (int64, error) {
+	var n int64
+	buf := make([]byte, 32*1024)
+	for {
+		nr, err := body.Read(buf)
+		if nr > 0 {
+			nw, werr := f.Write(buf[:nr])
+			n += int64(nw)
+			p.add(int64(nw))
+			if werr != nil {
+				return n, werr
+			}
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, interrupted{err}
+		}
+	}
+}
+
+// verify checks the SHA-256 digest of the named file, if required.
+func (d *download) verify(name string) error {
+	if d.sha256 == nil {
+		return nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if sum := h.Sum(nil); !bytes.Equal(sum, d.sha256) {
+		return fmt.Errorf("SHA-256 digest is %x, want %x", sum, d.sha256)
+	}
+	return nil
+}
+
+// parseContentRange parses a Content-Range header of the form
+// "bytes start-end/size", in which size may be "*" (unknown).
+func parseContentRange(s string) (start, size int64, err error) {
+	var end int64
+	var total string
+	if _, err := fmt.Sscanf(s, "bytes %d-%d/%s", &start, &end, &total); err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", s)
+	}
+	size = -1
+	if total != "*" {
+		if size, err = strconv.ParseInt(total, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid Content-Range %q", s)
+		}
+	}
+	return start, size, nil
+}
+
+// meta holds the validators of a downloaded file.
+type meta struct {
+	ETag         string `json:",omitempty"`
+	LastModified string `json:",omitempty"`
+}
+
+// readMeta returns the validators of the named file, if known.
+func readMeta(name string) meta {
+	var m meta
+	data, err := ioutil.ReadFile(name + ".meta")
+	if err == nil {
+		json.Unmarshal(data, &m) // an invalid file has no validators
+	}
+	return m
+}
+
+func (m meta) write(name string) error {
+	data, _ := json.Marshal(m)
+	return ioutil.WriteFile(name+".meta", data, 0666)
+}
+
+// validator returns the value of an If-Range header that resumes the
+// file only if it is unchanged.  A weak ETag cannot be used.
+func (m meta) validator() string {
+	if m.ETag != "" && !strings.HasPrefix(m.ETag, "W/") {
+		return m.ETag
+	}
+	return m.LastModified
+}
+
+// A progress reports the progress of a transfer as a line that is
+// rewritten at most a few times a second.
+type progress struct {
+	out         io.Writer // if nil, nothing is reported
+	done, total int64     // total is -1 if unknown
+	start, last time.Time
+	n           int64 // bytes transferred since start
+}
+
+func (p *progress) add(n int64) {
+	p.done += n
+	p.n += n
+	if p.out != nil && time.Since(p.last) >= 200*time.Millisecond {
+		p.last = time.Now()
+		p.print()
+	}
+}
+
+func (p *progress) finish() {
+	if p.out != nil {
+		p.print()
+		fmt.Fprintln(p.out)
+	}
+}
+
+func (p *progress) print() {
+	var rate float64 // bytes per second
+	if secs := time.Since(p.start).Seconds(); secs > 0 {
+		rate = float64(p.n) / secs
+	}
+	if p.total < 0 {
+		fmt.Fprintf(p.out, "\r%s  %s/s ", bytesize(float64(p.done)), bytesize(rate))
+		return
+	}
+	percent := 100.0 // of an empty file
+	if p.total > 0 {
+		percent = 100 * float64(p.done) / float64(p.total)
+	}
+	eta := "?"
+	if rate > 0 {
+		eta = time.Duration(float64(p.total-p.done) / rate * float64(time.Second)).Round(time.Second).String()
+	}
+	fmt.Fprintf(p.out, "\r%s of %s (%.0f%%)  %s/s  ETA %s ",
+		bytesize(float64(p.done)), bytesize(float64(p.total)),
+		percent, bytesize(rate), eta)
+}
+
+// bytesize formats a number of bytes using the units kB, MB, ...
+func bytesize(n float64) string {
+	const units = "kMGTPE"
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return "? B"
+	}
+	if n < 1000 {
+		return fmt.Sprintf("%.0f B", n)
+	}
+	i := -1
+	for n >= 1000 && i < len(units)-1 {
+		n /= 1000
+		i++
+	}
+	return fmt.Sprintf("%.1f %cB", n, units[i])
+}
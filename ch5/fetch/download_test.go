@@ -0,0 +1,219 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A server serves a file, supporting ranges and conditional requests.
+// It can be made to drop the connection partway through a response.
+type server struct {
+	data     []byte
+	etag     string
+	modtime  time.Time
+	cut      int      // if positive, drop the connection after this many bytes
+	requests []string // the Range header of each request, or "-"
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rng := r.Header.Get("Range")
+	if rng == "" {
+		rng = "-"
+	}
+	s.requests = append(s.requests, rng)
+	if s.etag != "" {
+		w.Header().Set("ETag", s.etag)
+	}
+	if s.cut > 0 && r.Header.Get("Range") == "" {
+		w.Header().Set("Content-Length", fmt.Sprint(len(s.data)))
+		w.Header().Set("Last-Modified", s.modtime.UTC().Format(http.TimeFormat))
+		w.Write(s.data[:s.cut])
+		w.(http.Flusher).Flush()
+		s.cut = 0
+		panic(http.ErrAbortHandler) // drop the connection
+	}
+	http.ServeContent(w, r, "", s.modtime, bytes.NewReader(s.data))
+}
+
+func newDownload(t *testing.T, s *server) (*download, func()) {
+	ts := httptest.NewServer(s)
+	dir, err := ioutil.TempDir("", "fetch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &download{
+		client:  ts.Client(),
+		url:     ts.URL + "/file",
+		local:   filepath.Join(dir, "file"),
+		retries: 1,
+	}
+	return d, func() { ts.Close(); os.RemoveAll(dir) }
+}
+
+func randomData(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+func check(t *testing.T, d *download, want []byte) {
+	t.Helper()
+	got, err := ioutil.ReadFile(d.local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("downloaded %d bytes, differing from the %d served", len(got), len(want))
+	}
+	if _, err := os.Stat(d.local + ".part"); !os.IsNotExist(err) {
+		t.Errorf("partial file remains: %v", err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	s := &server{data: randomData(100000), etag: `"v1"`, modtime: time.Now(), cut: 30000}
+	d, cleanup := newDownload(t, s)
+	defer cleanup()
+	sum := sha256.Sum256(s.data)
+	d.sha256 = sum[:]
+	var progress bytes.Buffer
+	d.progress = &progress
+
+	n, modified, err := d.run()
+	if err != nil || n != 100000 || !modified {
+		t.Fatalf("run() = %d, %t, %v", n, modified, err)
+	}
+	check(t, d, s.data)
+	if got, want := strings.Join(s.requests, " "), "- bytes=30000-"; got != want {
+		t.Errorf("requests = %s, want %s", got, want)
+	}
+	if !strings.Contains(progress.String(), "resuming") || !strings.Contains(progress.String(), "100.0 kB of 100.0 kB (100%)") {
+		t.Errorf("progress:\n%s", &progress)
+	}
+
+	// The file is unchanged, so a second run fetches nothing.
+	s.requests = nil
+	n, modified, err = d.run()
+	if err != nil || n != 100000 || modified {
+		t.Fatalf("second run() = %d, %t, %v", n, modified, err)
+	}
+	if len(s.requests) != 1 {
+		t.Errorf("requests = %q, want one", s.requests)
+	}
+
+	// The file changes, so it is fetched again.
+	s.data = randomData(5000)
+	s.etag = `"v2"`
+	d.sha256 = nil
+	if _, modified, err := d.run(); err != nil || !modified {
+		t.Fatalf("third run() = %t, %v", modified, err)
+	}
+	check(t, d, s.data)
+}
+
+// TestStalePart checks that a partial file from an earlier run is
+// resumed only if the file on the server has not changed.
+func TestStalePart(t *testing.T) {
+	for _, etag := range []string{`"v1"`, `"v2"`} {
+		s := &server{data: randomData(10000), etag: `"v1"`, modtime: time.Now()}
+		d, cleanup := newDownload(t, s)
+		ioutil.WriteFile(d.local+".part", s.data[:4000], 0666)
+		meta{ETag: etag}.write(d.local + ".part")
+
+		if _, _, err := d.run(); err != nil {
+			t.Fatal(err)
+		}
+		check(t, d, s.data)
+		if got := readMeta(d.local); got.ETag != `"v1"` {
+			t.Errorf("meta = %+v, want ETag \"v1\"", got)
+		}
+		cleanup()
+	}
+}
+
+func TestLastModified(t *testing.T) {
+	s := &server{data: randomData(1000), modtime: time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC), cut: 500}
+	d, cleanup := newDownload(t, s)
+	defer cleanup()
+	if _, _, err := d.run(); err != nil {
+		t.Fatal(err)
+	}
+	check(t, d, s.data)
+	if _, modified, err := d.run(); err != nil || modified {
+		t.Errorf("second run() = %t, %v, want not modified", modified, err)
+	}
+	if got, want := strings.Join(s.requests, " "), "- bytes=500- -"; got != want {
+		t.Errorf("requests = %s, want %s", got, want)
+	}
+}
+
+func TestBadDigest(t *testing.T) {
+	s := &server{data: randomData(1000), etag: `"v1"`, modtime: time.Now()}
+	d, cleanup := newDownload(t, s)
+	defer cleanup()
+	d.sha256 = make([]byte, sha256.Size)
+	_, _, err := d.run()
+	if err == nil || !strings.Contains(err.Error(), "SHA-256 digest is") {
+		t.Errorf("run() = %v, want digest mismatch", err)
+	}
+	for _, name := range []string{d.local, d.local + ".part"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%s exists after a bad digest", filepath.Base(name))
+		}
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	for _, test := range []struct {
+		s           string
+		start, size int64
+	}{
+		{"bytes 0-9/10", 0, 10},
+		{"bytes 5-9/*", 5, -1},
+		{"bytes */10", 0, 0}, // invalid
+	} {
+		start, size, err := parseContentRange(test.s)
+		if start != test.start || size != test.size || (err != nil) != (test.size == 0) {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v", test.s, start, size, err)
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	// An empty file, just begun.
+	var out bytes.Buffer
+	p := &progress{out: &out, total: 0, start: time.Now()}
+	p.finish()
+	if got, want := out.String(), "\r0 B of 0 B (100%)  0 B/s  ETA ? \n"; got != want {
+		t.Errorf("progress = %q, want %q", got, want)
+	}
+
+	for _, test := range []struct {
+		n    float64
+		want string
+	}{
+		{999, "999 B"},
+		{1500, "1.5 kB"},
+		{2e18, "2.0 EB"},
+		{math.NaN(), "? B"},
+		{math.Inf(1), "? B"},
+	} {
+		if got := bytesize(test.n); got != test.want {
+			t.Errorf("bytesize(%g) = %s, want %s", test.n, got, test.want)
+		}
+	}
+}
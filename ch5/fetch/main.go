@@ -3,65 +3,95 @@
 
 // See page 148.
 
-// Fetch saves the contents of a URL into a local file.
+// Fetch saves the contents of a URL into a local file.  An interrupted
+// download is resumed, on this or a later run, and a complete file is
+// fetched again only if it has changed on the server.
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
 	"fmt"
-	"io"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"path"
 )
 
-/**
-It's tempting to use a second deferred call, to f.Close, to close the local file, but this would be subtly wrong because
-os.Create opens a file for writing, creating it as needed(os.Create是按需创建文件). On many file systems, notably(adv. 显著地；尤其) NFS,
-write errors are not reported immediately but may be postponed until the file is closed. Failure
-to check the result of the close operation could cause serious data loss to go unnoticed(go unnoticed:不被差觉的).
-However, if both io.Copy and f.Close fail, we should prefer to report the error from
-io.Copy since it occurred first and is more likely to tell us the root cause.
- */
-
 //!+
 // Fetch downloads the URL and returns the
 // name and length of the local file.
 func fetch(url string) (filename string, n int64, err error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", 0, err
+	local := localName(url)
+	d := &download{
+		client:  http.DefaultClient,
+		url:     url,
+		local:   local,
+		sha256:  digest,
+		retries: *retries,
 	}
-	defer resp.Body.Close()
-	// path.Base 文档中提到: If the path consists entirely of slashes, Base returns "/".
-	local := path.Base(resp.Request.URL.Path)
-	if local == "/" {
-		local = "index.html"
+	if !*quiet {
+		d.progress = os.Stderr
 	}
-	f, err := os.Create(local)
-	if err != nil {
-		return "", 0, err
+	n, modified, err := d.run()
+	if err == nil && !modified {
+		fmt.Fprintf(os.Stderr, "%s: %s is up to date.\n", url, local)
 	}
-	// resp.Body 类型为 io.ReadCloser
-	n, err = io.Copy(f, resp.Body)
-	// Close file, but prefer error from Copy, if any.
-	if closeErr := f.Close(); err == nil {
-		// 如果 io.Copy 返回的 err 不是 nil, 则优先返回 io.Copy 的错误
-		// 否则,如果 io.Copy 没有出错, 则将 err 变量设置为 f.Close() 的返回值
-		err = closeErr
-	}
-
 	return local, n, err
 }
 
 //!-
 
+// localName returns the name of the file for url: the -o flag, or
+// else the last element of its path.
+func localName(url string) string {
+	if *output != "" {
+		return *output
+	}
+	// path.Base 文档中提到: If the path consists entirely of slashes, Base returns "/".
+	local := "/"
+	if u, err := neturl.Parse(url); err == nil {
+		local = path.Base(u.Path)
+	}
+	if local == "/" || local == "." {
+		local = "index.html"
+	}
+	return local
+}
+
+var (
+	output  = flag.String("o", "", "name of the local file, if there is only one URL")
+	sum     = flag.String("sha256", "", "expected SHA-256 digest, in hexadecimal, if there is only one URL")
+	retries = flag.Int("retries", 5, "number of times to resume an interrupted download")
+	quiet   = flag.Bool("q", false, "don't report progress")
+
+	digest []byte // the decoded -sha256 flag
+)
+
 func main() {
-	for _, url := range os.Args[1:] {
+	flag.Parse()
+	if (*output != "" || *sum != "") && flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "fetch: -o and -sha256 require a single URL")
+		os.Exit(1)
+	}
+	if *sum != "" {
+		var err error
+		digest, err = hex.DecodeString(*sum)
+		if err != nil || len(digest) != sha256.Size {
+			fmt.Fprintf(os.Stderr, "fetch: invalid -sha256 digest %q\n", *sum)
+			os.Exit(1)
+		}
+	}
+	status := 0
+	for _, url := range flag.Args() {
 		local, n, err := fetch(url)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "fetch %s: %v\n", url, err)
+			status = 1
 			continue
 		}
 		fmt.Fprintf(os.Stderr, "%s => %s (%d bytes).\n", url, local, n)
 	}
+	os.Exit(status)
 }
@@ -3,8 +3,11 @@
 
 package eval
 
+import "text/scanner"
+
 // This package exports only the types Expr, Env, Var; clients can use the evaluator
-// without access to the other expression types.
+// without access to the other expression types.  (Other packages may use
+// Walk, Rewrite and the accessors in walk.go to examine and build them.)
 
 // An Expr is an arithmetic expression.
 type Expr interface {
@@ -19,25 +22,76 @@ type Expr interface {
 // A Var identifies a variable, e.g., x.
 type Var string
 
-// A literal is a numeric constant, e.g., 3.141.
-type literal float64
+// A literal is a numeric constant, e.g., 3.141, or an imaginary
+// constant, e.g., 2i, which has no value as a real number.
+type literal struct {
+	val  float64 // the value, or the imaginary part if imag
+	imag bool
+	text string // source text, if known, for exact evaluation
+	pos  scanner.Position
+}
+
+// An ident is a reference to a variable, e.g., x.
+// The parser produces an ident, not a Var, so that
+// the position of each reference is known.
+type ident struct {
+	name Var
+	pos  scanner.Position
+}
 
 // A unary represents a unary operator expression, e.g., -x.
 type unary struct {
-	op rune // one of '+', '-'
-	x  Expr
+	op  rune // one of '+', '-', '!'
+	x   Expr
+	pos scanner.Position // position of op
 }
 
 // A binary represents a binary operator expression, e.g., x+y.
 type binary struct {
-	op   rune // one of '+', '-', '*', '/'
+	op   rune // one of '+', '-', '*', '/', '<', '>', le, ge, eq, ne, and, or
 	x, y Expr
+	pos  scanner.Position // position of op
+}
+
+// A conditional represents a conditional expression, e.g., x > 0 ? x : 0.
+type conditional struct {
+	cond, x, y Expr
+	pos        scanner.Position // position of '?'
 }
 
 // A call represents a function call expression, e.g., sin(x).
 type call struct {
-	fn   string // one of "pow", "sin", "sqrt"
+	fn   string // a key of Funcs, e.g., "sin"
 	args []Expr
+	pos  scanner.Position // position of fn
 }
 
 //!-ast
+
+// Positions are recorded by Parse; the position of a node built
+// in any other way, such as by Derive, may be the zero Position.
+
+// num returns a literal with no position.
+func num(x float64) literal { return literal{val: x} }
+
+// Tokens for the two-character operators.  Like the token values
+// defined by text/scanner, they are negative so that they cannot
+// be confused with an ordinary rune.
+const (
+	le  = -(iota + 100) // <=
+	ge                  // >=
+	eq                  // ==
+	ne                  // !=
+	and                 // &&
+	or                  // ||
+)
+
+var opText = map[rune]string{le: "<=", ge: ">=", eq: "==", ne: "!=", and: "&&", or: "||"}
+
+// opString returns the source text of the operator op.
+func opString(op rune) string {
+	if s, ok := opText[op]; ok {
+		return s
+	}
+	return string(op)
+}
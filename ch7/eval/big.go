@@ -0,0 +1,335 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// A BigEnv maps variables to arbitrary-precision values, for EvalBig.
+type BigEnv map[Var]*big.Float
+
+// A RatEnv maps variables to exact rational values, for EvalRat.
+type RatEnv map[Var]*big.Rat
+
+// EvalBig returns the value of e, which should already have been
+// checked, computed using floating-point numbers with a mantissa of
+// prec bits.  Literals are converted from their source text, so 0.1
+// is as precise as prec allows.
+//
+// Only the functions abs, ceil, floor, max, min, pow (with an integer
+// exponent) and sqrt are supported.  Comparison and logical operators
+// yield 1 or 0, as with Eval.  An operation whose result is not a
+// number, such as 0/0, is an error.
+func EvalBig(e Expr, env BigEnv, prec uint) (_ *big.Float, err error) {
+	defer func() {
+		switch x := recover().(type) {
+		case nil:
+			// no panic
+		case evalPanic:
+			err = fmt.Errorf("%s", x)
+		case big.ErrNaN:
+			err = x
+		default:
+			panic(x)
+		}
+	}()
+	return (&bigEvaluator{env, prec}).eval(e), nil
+}
+
+type bigEvaluator struct {
+	env  BigEnv
+	prec uint
+}
+
+func (b *bigEvaluator) new() *big.Float { return new(big.Float).SetPrec(b.prec) }
+
+func (b *bigEvaluator) truth(t bool) *big.Float { return b.new().SetFloat64(truth(t)) }
+
+func (b *bigEvaluator) eval(e Expr) *big.Float {
+	switch e := e.(type) {
+	case literal:
+		if e.imag {
+			panic(evalPanic(fmt.Sprintf("imaginary literal %s is not a real number", Format(e))))
+		}
+		if e.text != "" {
+			if z, ok := b.new().SetString(e.text); ok {
+				return z
+			}
+		}
+		return b.new().SetFloat64(e.val)
+
+	case Var:
+		return b.variable(e)
+
+	case ident:
+		return b.variable(e.name)
+
+	case unary:
+		x := b.eval(e.x)
+		switch e.op {
+		case '+':
+			return x
+		case '-':
+			return b.new().Neg(x)
+		case '!':
+			return b.truth(x.Sign() == 0)
+		}
+
+	case binary:
+		x := b.eval(e.x)
+		switch e.op {
+		case and:
+			return b.truth(x.Sign() != 0 && b.eval(e.y).Sign() != 0)
+		case or:
+			return b.truth(x.Sign() != 0 || b.eval(e.y).Sign() != 0)
+		}
+		y := b.eval(e.y)
+		switch e.op {
+		case '+':
+			return b.new().Add(x, y)
+		case '-':
+			return b.new().Sub(x, y)
+		case '*':
+			return b.new().Mul(x, y)
+		case '/':
+			return b.new().Quo(x, y)
+		}
+		return b.truth(compare(e.op, x.Cmp(y)))
+
+	case conditional:
+		if b.eval(e.cond).Sign() != 0 {
+			return b.eval(e.x)
+		}
+		return b.eval(e.y)
+
+	case call:
+		args := make([]*big.Float, len(e.args))
+		for i, arg := range e.args {
+			args[i] = b.eval(arg)
+		}
+		return b.call(e.fn, args)
+	}
+	panic(evalPanic(fmt.Sprintf("cannot evaluate %s with big.Float", Format(e))))
+}
+
+func (b *bigEvaluator) variable(v Var) *big.Float {
+	x, ok := b.env[v]
+	if !ok {
+		return b.new() // zero, as with Eval
+	}
+	return b.new().Set(x)
+}
+
+func (b *bigEvaluator) call(fn string, args []*big.Float) *big.Float {
+	switch fn {
+	case "abs":
+		return b.new().Abs(args[0])
+	case "sqrt":
+		return b.new().Sqrt(args[0])
+	case "floor", "ceil":
+		x := args[0]
+		if x.IsInt() || x.IsInf() {
+			return x
+		}
+		i, _ := x.Int(nil) // truncates toward zero
+		if fn == "floor" && x.Sign() < 0 {
+			i.Sub(i, big.NewInt(1))
+		} else if fn == "ceil" && x.Sign() > 0 {
+			i.Add(i, big.NewInt(1))
+		}
+		return b.new().SetInt(i)
+	case "min", "max":
+		z := args[0]
+		for _, x := range args[1:] {
+			if c := x.Cmp(z); fn == "min" && c < 0 || fn == "max" && c > 0 {
+				z = x
+			}
+		}
+		return z
+	case "pow":
+		if !args[1].IsInt() {
+			panic(evalPanic("pow requires an integer exponent"))
+		}
+		n, _ := args[1].Int(nil)
+		z := b.new().SetInt64(1)
+		x := b.new().Set(args[0])
+		for m := new(big.Int).Abs(n); m.Sign() > 0; m.Rsh(m, 1) {
+			if m.Bit(0) == 1 {
+				z.Mul(z, x)
+			}
+			x.Mul(x, x)
+		}
+		if n.Sign() < 0 {
+			z.Quo(b.new().SetInt64(1), z)
+		}
+		return z
+	}
+	panic(evalPanic(fmt.Sprintf("function %s is not supported by EvalBig", fn)))
+}
+
+// EvalRat returns the exact value of e, which should already have been
+// checked, computed using rational numbers.  Literals are converted
+// from their source text, so 0.1 is exactly one tenth.
+//
+// Only the functions abs, ceil, floor, max, min and pow (with an
+// integer exponent) are supported.  Comparison and logical operators
+// yield 1 or 0, as with Eval.  Division by zero is an error.
+func EvalRat(e Expr, env RatEnv) (_ *big.Rat, err error) {
+	defer recoverEval(&err)
+	return evalRat(e, env), nil
+}
+
+func ratTruth(t bool) *big.Rat { return big.NewRat(int64(truth(t)), 1) }
+
+func evalRat(e Expr, env RatEnv) *big.Rat {
+	switch e := e.(type) {
+	case literal:
+		if e.imag {
+			panic(evalPanic(fmt.Sprintf("imaginary literal %s is not a real number", Format(e))))
+		}
+		if e.text != "" {
+			if z, ok := new(big.Rat).SetString(e.text); ok {
+				return z
+			}
+		}
+		z := new(big.Rat)
+		if z.SetFloat64(e.val) == nil {
+			panic(evalPanic(fmt.Sprintf("literal %g is not a rational number", e.val)))
+		}
+		return z
+
+	case Var:
+		return ratVariable(env, e)
+
+	case ident:
+		return ratVariable(env, e.name)
+
+	case unary:
+		x := evalRat(e.x, env)
+		switch e.op {
+		case '+':
+			return x
+		case '-':
+			return new(big.Rat).Neg(x)
+		case '!':
+			return ratTruth(x.Sign() == 0)
+		}
+
+	case binary:
+		x := evalRat(e.x, env)
+		switch e.op {
+		case and:
+			return ratTruth(x.Sign() != 0 && evalRat(e.y, env).Sign() != 0)
+		case or:
+			return ratTruth(x.Sign() != 0 || evalRat(e.y, env).Sign() != 0)
+		}
+		y := evalRat(e.y, env)
+		switch e.op {
+		case '+':
+			return new(big.Rat).Add(x, y)
+		case '-':
+			return new(big.Rat).Sub(x, y)
+		case '*':
+			return new(big.Rat).Mul(x, y)
+		case '/':
+			if y.Sign() == 0 {
+				panic(evalPanic("division by zero"))
+			}
+			return new(big.Rat).Quo(x, y)
+		}
+		return ratTruth(compare(e.op, x.Cmp(y)))
+
+	case conditional:
+		if evalRat(e.cond, env).Sign() != 0 {
+			return evalRat(e.x, env)
+		}
+		return evalRat(e.y, env)
+
+	case call:
+		args := make([]*big.Rat, len(e.args))
+		for i, arg := range e.args {
+			args[i] = evalRat(arg, env)
+		}
+		return callRat(e.fn, args)
+	}
+	panic(evalPanic(fmt.Sprintf("cannot evaluate %s with big.Rat", Format(e))))
+}
+
+func ratVariable(env RatEnv, v Var) *big.Rat {
+	x, ok := env[v]
+	if !ok {
+		return new(big.Rat) // zero, as with Eval
+	}
+	return new(big.Rat).Set(x)
+}
+
+func callRat(fn string, args []*big.Rat) *big.Rat {
+	switch fn {
+	case "abs":
+		return new(big.Rat).Abs(args[0])
+	case "floor", "ceil":
+		x := args[0]
+		if x.IsInt() {
+			return x
+		}
+		// The quotient of Int.Div rounds toward negative infinity
+		// when the divisor (here, the denominator) is positive.
+		i := new(big.Int).Div(x.Num(), x.Denom())
+		if fn == "ceil" {
+			i.Add(i, big.NewInt(1))
+		}
+		return new(big.Rat).SetInt(i)
+	case "min", "max":
+		z := args[0]
+		for _, x := range args[1:] {
+			if c := x.Cmp(z); fn == "min" && c < 0 || fn == "max" && c > 0 {
+				z = x
+			}
+		}
+		return z
+	case "pow":
+		if !args[1].IsInt() {
+			panic(evalPanic("pow requires an integer exponent"))
+		}
+		n := args[1].Num()
+		if n.Sign() < 0 && args[0].Sign() == 0 {
+			panic(evalPanic("division by zero"))
+		}
+		z := new(big.Rat).SetInt64(1)
+		x := new(big.Rat).Set(args[0])
+		for m := new(big.Int).Abs(n); m.Sign() > 0; m.Rsh(m, 1) {
+			if m.Bit(0) == 1 {
+				z.Mul(z, x)
+			}
+			x.Mul(x, x)
+		}
+		if n.Sign() < 0 {
+			z.Inv(z)
+		}
+		return z
+	}
+	panic(evalPanic(fmt.Sprintf("function %s is not supported by EvalRat", fn)))
+}
+
+// compare reports whether the result c of a Cmp method
+// satisfies the comparison operator op.
+func compare(op rune, c int) bool {
+	switch op {
+	case '<':
+		return c < 0
+	case '>':
+		return c > 0
+	case le:
+		return c <= 0
+	case ge:
+		return c >= 0
+	case eq:
+		return c == 0
+	case ne:
+		return c != 0
+	}
+	panic(evalPanic(fmt.Sprintf("unsupported binary operator: %s", opString(op))))
+}
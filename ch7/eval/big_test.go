@@ -0,0 +1,234 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"math"
+	"math/big"
+	"math/cmplx"
+	"strings"
+	"testing"
+)
+
+// near reports whether x and y are equal to about 12 digits.
+func near(x, y float64) bool {
+	return x == y || math.Abs(x-y) <= 1e-12*math.Max(math.Abs(x), math.Abs(y))
+}
+
+// unsupported reports whether err is due to a function
+// that is not available in some evaluation mode.
+func unsupported(err error) bool {
+	return strings.Contains(err.Error(), "not supported") ||
+		strings.Contains(err.Error(), "not defined")
+}
+
+// TestModes cross-checks EvalBig, EvalRat and EvalComplex against Eval.
+func TestModes(t *testing.T) {
+	for _, test := range evalTests {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		want := expr.Eval(test.env)
+
+		benv, renv, cenv := BigEnv{}, RatEnv{}, ComplexEnv{}
+		for v, x := range test.env {
+			benv[v] = big.NewFloat(x)
+			renv[v] = new(big.Rat).SetFloat64(x)
+			cenv[v] = complex(x, 0)
+		}
+
+		if z, err := EvalBig(expr, benv, 53); err != nil {
+			if !unsupported(err) {
+				t.Errorf("EvalBig(%s): %v", test.expr, err)
+			}
+		} else if got, _ := z.Float64(); !near(got, want) {
+			t.Errorf("EvalBig(%s) in %v = %g, want %g", test.expr, test.env, got, want)
+		}
+
+		if z, err := EvalRat(expr, renv); err != nil {
+			if !unsupported(err) {
+				t.Errorf("EvalRat(%s): %v", test.expr, err)
+			}
+		} else if got, _ := z.Float64(); !near(got, want) {
+			t.Errorf("EvalRat(%s) in %v = %g, want %g", test.expr, test.env, got, want)
+		}
+
+		if z, err := EvalComplex(expr, cenv); err != nil {
+			if !unsupported(err) {
+				t.Errorf("EvalComplex(%s): %v", test.expr, err)
+			}
+		} else if !near(real(z), want) || math.Abs(imag(z)) > 1e-12 {
+			t.Errorf("EvalComplex(%s) in %v = %g, want %g", test.expr, test.env, z, want)
+		}
+	}
+}
+
+func TestEvalRat(t *testing.T) {
+	for _, test := range []struct{ expr, want string }{
+		{"0.1 + 0.2 == 0.3", "1"},
+		{"1 / 3 + 1 / 6", "1/2"},
+		{"pow(2 / 3, -3)", "27/8"},
+		{"floor(-7 / 2) + ceil(7 / 2)", "0"},
+		{"max(1 / 3, 0.33, 1 / 4)", "1/3"},
+	} {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		z, err := EvalRat(expr, nil)
+		if err != nil {
+			t.Errorf("EvalRat(%s): %v", test.expr, err)
+			continue
+		}
+		if got := z.RatString(); got != test.want {
+			t.Errorf("EvalRat(%s) = %s, want %s", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestEvalBig(t *testing.T) {
+	expr, err := Parse("sqrt(2) * sqrt(2) - 2 + 1 / 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := EvalBig(expr, nil, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := z.Text('g', 50), "0.33333333333333333333333333333333333333333333333333"; got != want {
+		t.Errorf("EvalBig = %s, want %s", got, want)
+	}
+}
+
+func TestEvalComplex(t *testing.T) {
+	for _, test := range []struct {
+		expr string
+		env  ComplexEnv
+		want complex128
+	}{
+		{"exp(1i * pi) + 1", ComplexEnv{"pi": math.Pi}, 0},
+		{"abs(3 + 4i)", nil, 5},
+		{"arg(z)", ComplexEnv{"z": 1i}, math.Pi / 2},
+		{"conj(z) * z", ComplexEnv{"z": 2 + 3i}, 13},
+		{"sqrt(z)", ComplexEnv{"z": -4}, 2i},
+		{"real(z) < imag(z) ? 1 : 2i", ComplexEnv{"z": 1 + 2i}, 1},
+	} {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		got, err := EvalComplex(expr, test.env)
+		if err != nil {
+			t.Errorf("EvalComplex(%s): %v", test.expr, err)
+			continue
+		}
+		if cmplx.Abs(got-test.want) > 1e-12 {
+			t.Errorf("EvalComplex(%s) = %g, want %g", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestModeErrors(t *testing.T) {
+	for _, test := range []struct {
+		expr string
+		eval func(Expr) error
+		want string
+	}{
+		{"1i < 2", func(e Expr) error { _, err := EvalComplex(e, nil); return err },
+			"complex operands of < are not ordered: (0+1i), (2+0i)"},
+		{"atan2(1i, 1)", func(e Expr) error { _, err := EvalComplex(e, nil); return err },
+			"function atan2 is not defined for complex numbers"},
+		{"1 / 0", func(e Expr) error { _, err := EvalRat(e, nil); return err },
+			"division by zero"},
+		{"sin(1)", func(e Expr) error { _, err := EvalRat(e, nil); return err },
+			"function sin is not supported by EvalRat"},
+		{"2i", func(e Expr) error { _, err := EvalBig(e, nil, 64); return err },
+			"imaginary literal 2i is not a real number"},
+		{"pow(2, 0.5)", func(e Expr) error { _, err := EvalBig(e, nil, 64); return err },
+			"pow requires an integer exponent"},
+		{"0 / 0", func(e Expr) error { _, err := EvalBig(e, nil, 64); return err },
+			"division of zero by zero or infinity by infinity"},
+	} {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if err := test.eval(expr); err == nil || err.Error() != test.want {
+			t.Errorf("%s: got error %v, want %s", test.expr, err, test.want)
+		}
+	}
+}
+
+func TestImaginaryLiteral(t *testing.T) {
+	expr, err := Parse("2i + 1.5e3i")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Format(expr), "(2i + 1500i)"; got != want {
+		t.Errorf("Format = %s, want %s", got, want)
+	}
+	if got := expr.Eval(nil); !math.IsNaN(got) {
+		t.Errorf("Eval = %g, want NaN", got)
+	}
+
+	// Check rejects imaginary literals, which have no real value.
+	err = CheckAll(expr, nil)
+	want := "1:1: imaginary literal 2i is not a real number (use EvalComplex) (and 1 more errors)"
+	if err == nil || err.Error() != want {
+		t.Errorf("CheckAll = %v, want %s", err, want)
+	}
+	if err := expr.Check(map[Var]bool{}); err == nil {
+		t.Errorf("Check succeeded, want error")
+	}
+	if _, err := EvalComplex(expr, nil); err != nil {
+		t.Errorf("EvalComplex: %v", err)
+	}
+}
+
+func TestCheckComplex(t *testing.T) {
+	x := map[Var]bool{"x": true}
+	for _, test := range []struct {
+		input string
+		want  string // the error, or "" for success
+	}{
+		{"2i*x", ""},
+		{"sqrt(-1) + conj(x)", ""},
+		{"sqrt()", "1:1: call to sqrt has 0 args, want 1"},
+		{"pow(1i)", "1:1: call to pow has 1 args, want 2"},
+		{"hypot(x, 1)", "1:1: function hypot is not defined for complex numbers"},
+		{"2i*y", "1:4: undefined variable: y"},
+	} {
+		expr, err := Parse(test.input)
+		if err != nil {
+			t.Errorf("%s: %v", test.input, err)
+			continue
+		}
+		got := ""
+		if err := CheckComplex(expr, x); err != nil {
+			got = err.Error()
+		}
+		if got != test.want {
+			t.Errorf("CheckComplex(%s) = %q, want %q", test.input, got, test.want)
+		}
+	}
+
+	// CheckAll still rejects imaginary literals.
+	expr, _ := Parse("2i*x")
+	if err := CheckAll(expr, x); err == nil {
+		t.Errorf("CheckAll(2i*x) succeeded, want error")
+	}
+
+	// EvalComplex reports a bad call rather than panicking.
+	for _, input := range []string{"sqrt()", "pow(1i)"} {
+		expr, _ := Parse(input)
+		if _, err := EvalComplex(expr, nil); err == nil {
+			t.Errorf("EvalComplex(%s) succeeded, want error", input)
+		}
+	}
+}
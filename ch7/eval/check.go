@@ -5,7 +5,7 @@ package eval
 
 import (
 	"fmt"
-	"strings"
+	"text/scanner"
 )
 
 
@@ -23,44 +23,197 @@ func (v Var) Check(vars map[Var]bool) error {
 	return nil
 }
 
-func (literal) Check(vars map[Var]bool) error {
+func (l literal) Check(vars map[Var]bool) error {
+	return checkFirst(l, vars)
+}
+
+func (id ident) Check(vars map[Var]bool) error {
+	vars[id.name] = true
 	return nil
 }
 
-func (u unary) Check(vars map[Var]bool) error {
-	if !strings.ContainsRune("+-", u.op) {
-		return fmt.Errorf("unexpected unary op %q", u.op)
+func (u unary) Check(vars map[Var]bool) error       { return checkFirst(u, vars) }
+func (b binary) Check(vars map[Var]bool) error      { return checkFirst(b, vars) }
+func (c conditional) Check(vars map[Var]bool) error { return checkFirst(c, vars) }
+func (c call) Check(vars map[Var]bool) error        { return checkFirst(c, vars) }
+
+//!-Check
+
+// checkFirst checks e and returns the first error, if any.
+func checkFirst(e Expr, vars map[Var]bool) error {
+	c := checker{vars: vars}
+	c.check(e)
+	if len(c.errs) > 0 {
+		return c.errs[0]
 	}
-	return u.x.Check(vars)
+	return nil
 }
 
-func (b binary) Check(vars map[Var]bool) error {
-	if !strings.ContainsRune("+-*/", b.op) {
-		return fmt.Errorf("unexpected binary op %q", b.op)
+// CheckAll reports all the errors in e, not just the first, as an
+// ErrorList sorted by position.  If allowed is non-nil, a reference
+// to any variable not in allowed is also an error.
+func CheckAll(e Expr, allowed map[Var]bool) error {
+	c := checker{vars: make(map[Var]bool), allowed: allowed}
+	c.check(e)
+	if len(c.errs) == 0 {
+		return nil
 	}
-	if err := b.x.Check(vars); err != nil {
-		return err
+	c.errs.Sort()
+	return c.errs
+}
+
+// CheckComplex is like CheckAll, but checks e for evaluation by
+// EvalComplex: imaginary literals are permitted, and each function
+// called must be in ComplexFuncs.
+func CheckComplex(e Expr, allowed map[Var]bool) error {
+	c := checker{vars: make(map[Var]bool), allowed: allowed, complex: true}
+	c.check(e)
+	if len(c.errs) == 0 {
+		return nil
 	}
-	return b.y.Check(vars)
+	c.errs.Sort()
+	return c.errs
 }
 
-func (c call) Check(vars map[Var]bool) error {
-	arity, ok := numParams[c.fn]
-	if !ok {
-		return fmt.Errorf("unknown function %q", c.fn)
+// A checker accumulates the variables and errors found in an Expr.
+type checker struct {
+	vars    map[Var]bool
+	allowed map[Var]bool // if non-nil, the permitted variables
+	complex bool         // whether e is for EvalComplex
+	errs    ErrorList
+}
+
+func (c *checker) errorf(pos scanner.Position, format string, args ...interface{}) {
+	c.errs = append(c.errs, &Error{pos, fmt.Sprintf(format, args...)})
+}
+
+// checkKind reports an error if e, described by what,
+// is not of kind want.
+func (c *checker) checkKind(pos scanner.Position, what string, e Expr, want kind) {
+	if got := kindOf(e); got != want {
+		c.errorf(pos, "%s is %s, want %s", what, got, want)
 	}
-	if len(c.args) != arity {
-		return fmt.Errorf("call to %s has %d args, want %d",
-			c.fn, len(c.args), arity)
+}
+
+func (c *checker) checkVar(pos scanner.Position, v Var) {
+	c.vars[v] = true
+	if c.allowed != nil && !c.allowed[v] {
+		c.errorf(pos, "undefined variable: %s", v)
 	}
-	for _, arg := range c.args {
-		if err := arg.Check(vars); err != nil {
-			return err
+}
+
+func (c *checker) check(e Expr) {
+	switch e := e.(type) {
+	case literal:
+		if e.imag && !c.complex {
+			c.errorf(e.pos, "imaginary literal %s is not a real number (use EvalComplex)", Format(e))
+		}
+
+	case Var:
+		c.checkVar(scanner.Position{}, e)
+
+	case ident:
+		c.checkVar(e.pos, e.name)
+
+	case unary:
+		switch e.op {
+		case '+', '-':
+			c.checkKind(e.pos, "operand of "+string(e.op), e.x, numeric)
+		case '!':
+			c.checkKind(e.pos, "operand of !", e.x, boolean)
+		default:
+			c.errorf(e.pos, "unexpected unary op %q", e.op)
+		}
+		c.check(e.x)
+
+	case binary:
+		if precedence(e.op) == 0 {
+			c.errorf(e.pos, "unexpected binary op %q", e.op)
+		} else {
+			want := numeric
+			if e.op == and || e.op == or {
+				want = boolean
+			}
+			what := "operand of " + opString(e.op)
+			c.checkKind(e.pos, what, e.x, want)
+			c.checkKind(e.pos, what, e.y, want)
+		}
+		c.check(e.x)
+		c.check(e.y)
+
+	case conditional:
+		c.checkKind(e.pos, "condition of ?:", e.cond, boolean)
+		if kx, ky := kindOf(e.x), kindOf(e.y); kx != ky {
+			c.errorf(e.pos, "mismatched operands of ?: (%s and %s)", kx, ky)
+		}
+		c.check(e.cond)
+		c.check(e.x)
+		c.check(e.y)
+
+	case call:
+		f, ok := Funcs[e.fn]
+		_, cok := ComplexFuncs[e.fn]
+		switch {
+		case !ok:
+			c.errorf(e.pos, "unknown function %q", e.fn)
+		case c.complex && !cok:
+			c.errorf(e.pos, "function %s is not defined for complex numbers", e.fn)
+		case f.Variadic && len(e.args) < f.Params:
+			c.errorf(e.pos, "call to %s has %d args, want at least %d",
+				e.fn, len(e.args), f.Params)
+		case !f.Variadic && len(e.args) != f.Params:
+			c.errorf(e.pos, "call to %s has %d args, want %d",
+				e.fn, len(e.args), f.Params)
+		}
+		for _, arg := range e.args {
+			c.checkKind(e.pos, "argument of "+e.fn, arg, numeric)
+		}
+		for _, arg := range e.args {
+			c.check(arg)
+		}
+
+	default:
+		// An Expr defined outside this package.
+		if err := e.Check(c.vars); err != nil {
+			if err, ok := err.(*Error); ok {
+				c.errs = append(c.errs, err)
+			} else {
+				c.errs = append(c.errs, &Error{Msg: err.Error()})
+			}
 		}
 	}
-	return nil
 }
 
-var numParams = map[string]int{"pow": 2, "sin": 1, "sqrt": 1}
+// A kind is the type of value an expression yields.  Boolean
+// values are represented as 1 and 0 during evaluation, but Check
+// does not allow them to be mixed with numbers.
+type kind int
 
-//!-Check
+const (
+	numeric kind = iota
+	boolean
+)
+
+func (k kind) String() string {
+	if k == boolean {
+		return "boolean"
+	}
+	return "numeric"
+}
+
+// kindOf returns the kind of value that e yields.
+func kindOf(e Expr) kind {
+	switch e := e.(type) {
+	case unary:
+		if e.op == '!' {
+			return boolean
+		}
+	case binary:
+		if precedence(e.op) <= precedence(eq) {
+			return boolean
+		}
+	case conditional:
+		return kindOf(e.x)
+	}
+	return numeric
+}
@@ -0,0 +1,277 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+// Calc is an interactive calculator for the expressions of gopl.io/ch7/eval.
+//
+// Usage:
+//
+//	calc [-n] [file...]
+//
+// Calc first runs the script files, if any, then reads lines from the
+// standard input.  Each line is an expression, whose value is printed,
+// an assignment, or a command:
+//
+//	let a = 3*x       assign the value of an expression to a variable
+//	:vars             list the variables and their values
+//	:clear [name...]  remove the named variables, or all of them
+//	:check expr       report all errors in expr and list its variables
+//	:format expr      print expr fully parenthesized
+//	:load file        run the lines of a script file
+//	:help             list the commands
+//	:quit             exit
+//
+// The value of the nth expression is saved in the variable _n, and
+// the most recent value in _.  Blank lines and lines beginning with #
+// are ignored.
+//
+// When the standard input is not a terminal, or if the -n flag is
+// given, calc prints no prompt and exits with status 1 after an error,
+// which makes it suitable for use in shell pipelines.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopl.io/ch7/eval"
+)
+
+var batch = flag.Bool("n", false, "non-interactive: no prompt; stop at the first error")
+
+func main() {
+	flag.Parse()
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+		*batch = true // input is not a terminal
+	}
+	c := newCalc(os.Stdout)
+	for _, file := range flag.Args() {
+		if err := c.load(file); err != nil {
+			fmt.Fprintf(os.Stderr, "calc: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := c.run(os.Stdin, "<stdin>", !*batch); err != nil {
+		fmt.Fprintf(os.Stderr, "calc: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// A calc holds the state of a calculator session.
+type calc struct {
+	env     eval.Env
+	results int // number of values computed so far
+	out     io.Writer
+	quit    bool
+	loading map[string]bool // absolute names of the files being loaded
+}
+
+func newCalc(out io.Writer) *calc {
+	return &calc{env: eval.Env{}, out: out, loading: make(map[string]bool)}
+}
+
+// run executes the lines read from in, whose name is used in
+// error messages, until the end of the input or a :quit command,
+// which may come from an earlier script.  If interactive, it prompts
+// for each line and reports errors without stopping.
+func (c *calc) run(in io.Reader, name string, interactive bool) error {
+	input := bufio.NewScanner(in)
+	for line := 1; !c.quit; line++ {
+		if interactive {
+			fmt.Fprint(c.out, "> ")
+		}
+		if !input.Scan() {
+			break
+		}
+		if err := c.exec(input.Text()); err != nil {
+			if !interactive {
+				return fmt.Errorf("%s:%d: %v", name, line, err)
+			}
+			fmt.Fprintln(c.out, err)
+		}
+	}
+	return input.Err()
+}
+
+// load executes the lines of a script file.  A script may load
+// others, but not one that is already being loaded.
+func (c *calc) load(file string) error {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	if c.loading[abs] {
+		return fmt.Errorf(":load: %s is already being loaded", file)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	c.loading[abs] = true
+	defer delete(c.loading, abs)
+	return c.run(f, file, false)
+}
+
+// exec executes a single line of input.
+func (c *calc) exec(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	if strings.HasPrefix(line, ":") {
+		cmd, arg := line, ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			cmd, arg = line[:i], strings.TrimSpace(line[i:])
+		}
+		return c.command(cmd, arg)
+	}
+	if strings.HasPrefix(line, "let ") {
+		return c.let(line[len("let "):])
+	}
+	x, err := c.eval(line)
+	if err != nil {
+		return err
+	}
+	c.results++
+	c.env[eval.Var(fmt.Sprintf("_%d", c.results))] = x
+	c.env["_"] = x
+	fmt.Fprintf(c.out, "%g\n", x)
+	return nil
+}
+
+// let executes an assignment "name = expr".
+func (c *calc) let(s string) error {
+	i := strings.Index(s, "=")
+	if i < 0 {
+		return fmt.Errorf("let: want name = expr")
+	}
+	name := strings.TrimSpace(s[:i])
+	if !isIdent(name) {
+		return fmt.Errorf("let: bad variable name %q", name)
+	}
+	x, err := c.eval(s[i+1:])
+	if err != nil {
+		return err
+	}
+	c.env[eval.Var(name)] = x
+	return nil
+}
+
+// eval parses, checks and evaluates the expression s.  Only the
+// variables already defined may be used.
+func (c *calc) eval(s string) (float64, error) {
+	expr, err := parse(s)
+	if err != nil {
+		return 0, err
+	}
+	allowed := make(map[eval.Var]bool)
+	for v := range c.env {
+		allowed[v] = true
+	}
+	if err := eval.CheckAll(expr, allowed); err != nil {
+		return 0, errors.New(eval.Explain(s, err))
+	}
+	return expr.Eval(c.env), nil
+}
+
+func (c *calc) command(cmd, arg string) error {
+	switch cmd {
+	case ":vars":
+		var names []string
+		for v := range c.env {
+			names = append(names, string(v))
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(c.out, "%s = %g\n", name, c.env[eval.Var(name)])
+		}
+
+	case ":clear":
+		if arg == "" {
+			c.env = eval.Env{}
+			c.results = 0
+			break
+		}
+		for _, name := range strings.Fields(arg) {
+			delete(c.env, eval.Var(name))
+		}
+
+	case ":check":
+		expr, err := parse(arg)
+		if err != nil {
+			return err
+		}
+		if err := eval.CheckAll(expr, nil); err != nil {
+			return errors.New(eval.Explain(arg, err))
+		}
+		vars := make(map[eval.Var]bool)
+		expr.Check(vars)
+		var names []string
+		for v := range vars {
+			names = append(names, string(v))
+		}
+		sort.Strings(names)
+		fmt.Fprintf(c.out, "ok; variables: %s\n", strings.Join(names, ", "))
+
+	case ":format":
+		expr, err := parse(arg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.out, eval.Format(expr))
+
+	case ":load":
+		if arg == "" {
+			return fmt.Errorf(":load: missing file name")
+		}
+		return c.load(arg)
+
+	case ":help":
+		fmt.Fprint(c.out, help)
+
+	case ":quit":
+		c.quit = true
+
+	default:
+		return fmt.Errorf("unknown command %s (try :help)", cmd)
+	}
+	return nil
+}
+
+const help = `expr              evaluate an expression; the nth value is saved as _n
+let a = expr      assign the value of an expression to a variable
+:vars             list the variables and their values
+:clear [name...]  remove the named variables, or all of them
+:check expr       report all errors in expr and list its variables
+:format expr      print expr fully parenthesized
+:load file        run the lines of a script file
+:help             list the commands
+:quit             exit
+`
+
+// parse parses s, describing any error with a caret beneath its position.
+func parse(s string) (eval.Expr, error) {
+	expr, err := eval.Parse(s)
+	if err != nil {
+		return nil, errors.New(eval.Explain(s, err))
+	}
+	return expr, nil
+}
+
+// isIdent reports whether s is a valid variable name.
+func isIdent(s string) bool {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
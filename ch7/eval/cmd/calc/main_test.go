@@ -0,0 +1,129 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// session runs the input through a new calculator and returns its
+// output and error.
+func session(input string, interactive bool) (string, error) {
+	var out bytes.Buffer
+	c := newCalc(&out)
+	err := c.run(strings.NewReader(input), "<test>", interactive)
+	return out.String(), err
+}
+
+func TestRun(t *testing.T) {
+	for _, test := range []struct {
+		input, want string
+	}{
+		{"1 + 2\n", "3\n"},
+		{"let x = 3\nx * x\n_ + _1\n", "9\n18\n"},
+		{"# comment\n\n  2  \n", "2\n"},
+		{"let a = 1\nlet b = 2\n:vars\n", "a = 1\nb = 2\n"},
+		{"let a = 1\nlet b = 2\n:clear a\n:vars\n", "b = 2\n"},
+		{"4\n:clear\n:vars\n5\n:vars\n", "4\n5\n_ = 5\n_1 = 5\n"},
+		{":check pow(x, 2) + y\n", "ok; variables: x, y\n"},
+		{":format 1 + 2 * x\n", "(1 + (2 * x))\n"},
+		{"1\n:quit\n2\n", "1\n"},
+	} {
+		got, err := session(test.input, false)
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+		} else if got != test.want {
+			t.Errorf("%q: got %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	// In batch mode, the first error stops the session.
+	for _, test := range []struct {
+		input, want string
+	}{
+		{"1\nx + 1\n2\n", "<test>:2: 1:1: undefined variable: x"},
+		{"let 2a = 1\n", `<test>:1: let: bad variable name "2a"`},
+		{"let a\n", "<test>:1: let: want name = expr"},
+		{":frob\n", "<test>:1: unknown command :frob (try :help)"},
+		{":load\n", "<test>:1: :load: missing file name"},
+		{"1 +\n", "<test>:1: 1:4: unexpected end of file"},
+	} {
+		_, err := session(test.input, false)
+		if err == nil || !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("%q: error %v, want %s", test.input, err, test.want)
+		}
+	}
+
+	// Interactively, errors are reported and the session continues.
+	got, err := session("x\n1\n", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "> ") || !strings.HasSuffix(got, "> 1\n> ") ||
+		!strings.Contains(got, "undefined variable: x") {
+		t.Errorf("interactive session: got %q", got)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	// The help lists every command in the package documentation.
+	got, _ := session(":help\n", false)
+	for _, cmd := range []string{"let", ":vars", ":clear", ":check", ":format", ":load", ":help", ":quit"} {
+		if !strings.Contains(got, "\n"+cmd+" ") {
+			t.Errorf(":help does not describe %s:\n%s", cmd, got)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, text string) string {
+		name = filepath.Join(dir, name)
+		if err := ioutil.WriteFile(name, []byte(text), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	lib := write("lib", "let r = 2\nlet area = 3 * r * r\n")
+	self := write("self", "let n = 1\n:load "+filepath.Join(dir, "self")+"\n")
+	cycle := write("a", ":load "+filepath.Join(dir, "b")+"\n")
+	write("b", ":load "+cycle+"\n")
+	quit := write("quit", "1\n:quit\n2\n")
+
+	got, err := session(":load "+lib+"\narea\n", false)
+	if err != nil || got != "12\n" {
+		t.Errorf(":load lib: got %q, %v", got, err)
+	}
+	for _, file := range []string{self, cycle} {
+		_, err := session(":load "+file+"\n", false)
+		if err == nil || !strings.Contains(err.Error(), "already being loaded") {
+			t.Errorf(":load %s: error %v, want recursive load error", file, err)
+		}
+	}
+
+	// A script that quits ends the session before the standard
+	// input is read.
+	var out bytes.Buffer
+	c := newCalc(&out)
+	if err := c.load(quit); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.run(strings.NewReader("3\n"), "<stdin>", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "1\n" {
+		t.Errorf("after :quit in a script: got %q, want %q", got, "1\n")
+	}
+}
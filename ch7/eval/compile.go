@@ -0,0 +1,259 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import "fmt"
+
+// A Program is an expression compiled to a flat sequence of
+// instructions for a stack machine.  Evaluating a Program avoids
+// the interface dispatch and map lookups of Expr.Eval, which makes
+// it well suited to evaluating one expression at many points.
+//
+// A Program is not safe for concurrent use, since it reuses its stack.
+type Program struct {
+	code  []instr
+	stack []float64
+}
+
+type opcode uint8
+
+const (
+	opConst     opcode = iota // push k
+	opLoad                    // push slots[n]
+	opNeg                     // x  => -x
+	opNot                     // x  => !x
+	opTruth                   // x  => x != 0
+	opAdd                     // x y => x + y
+	opSub                     // x y => x - y
+	opMul                     // x y => x * y
+	opDiv                     // x y => x / y
+	opLT                      // x y => x < y
+	opGT                      // x y => x > y
+	opLE                      // x y => x <= y
+	opGE                      // x y => x >= y
+	opEQ                      // x y => x == y
+	opNE                      // x y => x != y
+	opCall                    // n args => fn(args)
+	opJump                    // goto n
+	opJumpFalse               // pop x; if x == 0 goto n
+	opAndJump                 // if x == 0 goto n (leaving 0) else pop x
+	opOrJump                  // if x != 0 goto n (leaving 1) else pop x
+)
+
+// binaryOps maps each non-logical binary operator to its opcode.
+var binaryOps = map[rune]opcode{
+	'+': opAdd, '-': opSub, '*': opMul, '/': opDiv,
+	'<': opLT, '>': opGT, le: opLE, ge: opGE, eq: opEQ, ne: opNE,
+}
+
+type instr struct {
+	op opcode
+	n  int     // slot index, argument count, or jump target
+	k  float64 // constant
+	fn func([]float64) float64
+}
+
+// Compile compiles the expression e, which should already have been
+// checked, into a Program.  The value of vars[i] is supplied to
+// Program.Eval in slots[i]; it is an error for e to refer to any
+// other variable.  Functions are resolved from Funcs at compile time.
+func Compile(e Expr, vars []Var) (*Program, error) {
+	c := &compiler{slots: make(map[Var]int)}
+	for i, v := range vars {
+		c.slots[v] = i
+	}
+	if err := c.compile(e); err != nil {
+		return nil, err
+	}
+	return &Program{code: c.code, stack: make([]float64, c.max)}, nil
+}
+
+// A compiler holds the state of a call to Compile.
+type compiler struct {
+	slots      map[Var]int
+	code       []instr
+	depth, max int // current and maximum stack depth
+}
+
+// emit appends an instruction that changes the stack depth by delta,
+// and returns its index.
+func (c *compiler) emit(in instr, delta int) int {
+	c.code = append(c.code, in)
+	c.depth += delta
+	if c.depth > c.max {
+		c.max = c.depth
+	}
+	return len(c.code) - 1
+}
+
+// patch sets the target of the jump at index i to the next instruction.
+func (c *compiler) patch(i int) { c.code[i].n = len(c.code) }
+
+func (c *compiler) compile(e Expr) error {
+	switch e := e.(type) {
+	case literal:
+		c.emit(instr{op: opConst, k: e.Eval(nil)}, +1)
+
+	case ident:
+		return c.compile(e.name)
+
+	case Var:
+		n, ok := c.slots[e]
+		if !ok {
+			return fmt.Errorf("undefined variable: %s", e)
+		}
+		c.emit(instr{op: opLoad, n: n}, +1)
+
+	case unary:
+		if err := c.compile(e.x); err != nil {
+			return err
+		}
+		switch e.op {
+		case '-':
+			c.emit(instr{op: opNeg}, 0)
+		case '!':
+			c.emit(instr{op: opNot}, 0)
+		}
+
+	case binary:
+		if err := c.compile(e.x); err != nil {
+			return err
+		}
+		if e.op == and || e.op == or {
+			op := opAndJump
+			if e.op == or {
+				op = opOrJump
+			}
+			jump := c.emit(instr{op: op}, -1)
+			if err := c.compile(e.y); err != nil {
+				return err
+			}
+			c.emit(instr{op: opTruth}, 0)
+			c.patch(jump)
+			return nil
+		}
+		op, ok := binaryOps[e.op]
+		if !ok {
+			return fmt.Errorf("unsupported binary operator: %s", opString(e.op))
+		}
+		if err := c.compile(e.y); err != nil {
+			return err
+		}
+		c.emit(instr{op: op}, -1)
+
+	case conditional:
+		if err := c.compile(e.cond); err != nil {
+			return err
+		}
+		jumpElse := c.emit(instr{op: opJumpFalse}, -1)
+		if err := c.compile(e.x); err != nil {
+			return err
+		}
+		jumpEnd := c.emit(instr{op: opJump}, -1) // y replaces x on the other path
+		c.patch(jumpElse)
+		if err := c.compile(e.y); err != nil {
+			return err
+		}
+		c.patch(jumpEnd)
+
+	case call:
+		f, ok := Funcs[e.fn]
+		if !ok {
+			return fmt.Errorf("unknown function %q", e.fn)
+		}
+		for _, arg := range e.args {
+			if err := c.compile(arg); err != nil {
+				return err
+			}
+		}
+		n := len(e.args)
+		c.emit(instr{op: opCall, n: n, fn: f.Fn}, 1-n)
+
+	default:
+		return fmt.Errorf("unknown Expr: %T", e)
+	}
+	return nil
+}
+
+// Eval returns the value of the program when its variables
+// have the values in slots.
+func (p *Program) Eval(slots []float64) float64 {
+	s := p.stack
+	sp := 0 // stack pointer: s[sp-1] is the top
+	for pc := 0; pc < len(p.code); pc++ {
+		in := &p.code[pc]
+		switch in.op {
+		case opConst:
+			s[sp] = in.k
+			sp++
+		case opLoad:
+			s[sp] = slots[in.n]
+			sp++
+		case opNeg:
+			s[sp-1] = -s[sp-1]
+		case opNot:
+			s[sp-1] = truth(s[sp-1] == 0)
+		case opTruth:
+			s[sp-1] = truth(s[sp-1] != 0)
+		case opAdd:
+			sp--
+			s[sp-1] += s[sp]
+		case opSub:
+			sp--
+			s[sp-1] -= s[sp]
+		case opMul:
+			sp--
+			s[sp-1] *= s[sp]
+		case opDiv:
+			sp--
+			s[sp-1] /= s[sp]
+		case opLT:
+			sp--
+			s[sp-1] = truth(s[sp-1] < s[sp])
+		case opGT:
+			sp--
+			s[sp-1] = truth(s[sp-1] > s[sp])
+		case opLE:
+			sp--
+			s[sp-1] = truth(s[sp-1] <= s[sp])
+		case opGE:
+			sp--
+			s[sp-1] = truth(s[sp-1] >= s[sp])
+		case opEQ:
+			sp--
+			s[sp-1] = truth(s[sp-1] == s[sp])
+		case opNE:
+			sp--
+			s[sp-1] = truth(s[sp-1] != s[sp])
+		case opCall:
+			sp -= in.n
+			s[sp] = in.fn(s[sp : sp+in.n])
+			sp++
+		case opJump:
+			pc = in.n - 1
+		case opJumpFalse:
+			sp--
+			if s[sp] == 0 {
+				pc = in.n - 1
+			}
+		case opAndJump:
+			if s[sp-1] == 0 {
+				s[sp-1] = 0
+				pc = in.n - 1
+			} else {
+				sp--
+			}
+		case opOrJump:
+			if s[sp-1] != 0 {
+				s[sp-1] = 1
+				pc = in.n - 1
+			} else {
+				sp--
+			}
+		default:
+			panic(fmt.Sprintf("unknown opcode %d", in.op))
+		}
+	}
+	return s[0]
+}
@@ -0,0 +1,104 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+// compile parses, checks and compiles s, returning the program and
+// its variables in the order expected by Program.Eval.
+func compile(s string) (Expr, *Program, []Var, error) {
+	expr, err := Parse(s)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	set := make(map[Var]bool)
+	if err := expr.Check(set); err != nil {
+		return nil, nil, nil, err
+	}
+	var vars []Var
+	for v := range set {
+		vars = append(vars, v)
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i] < vars[j] })
+	prog, err := Compile(expr, vars)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return expr, prog, vars, nil
+}
+
+func TestCompile(t *testing.T) {
+	for _, test := range evalTests {
+		expr, prog, vars, err := compile(test.expr)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		slots := make([]float64, len(vars))
+		for i, v := range vars {
+			slots[i] = test.env[v]
+		}
+		want := expr.Eval(test.env)
+		got := prog.Eval(slots)
+		if math.Float64bits(got) != math.Float64bits(want) {
+			t.Errorf("%s: compiled program in %v = %g, want %g",
+				test.expr, test.env, got, want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	expr, err := Parse("x + y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Compile(expr, []Var{"x"})
+	if err == nil || err.Error() != "undefined variable: y" {
+		t.Errorf("Compile(x + y, [x]) returned error %v, want undefined variable", err)
+	}
+}
+
+// The benchmarks evaluate a formula at each corner of a
+// 100x100 grid, as ch7/surface does.
+const benchFormula = "r > 0 ? sin(r) / r * pow(cos(x / 5), 2) : 1"
+
+func BenchmarkEval(b *testing.B) {
+	expr, err := Parse(benchFormula)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for n := 0; n < b.N; n++ {
+		for i := 0; i <= 100; i++ {
+			for j := 0; j <= 100; j++ {
+				x, y := float64(i-50)*0.3, float64(j-50)*0.3
+				expr.Eval(Env{"x": x, "y": y, "r": math.Hypot(x, y)})
+			}
+		}
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	expr, err := Parse(benchFormula)
+	if err != nil {
+		b.Fatal(err)
+	}
+	prog, err := Compile(expr, []Var{"x", "y", "r"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	slots := make([]float64, 3)
+	for n := 0; n < b.N; n++ {
+		for i := 0; i <= 100; i++ {
+			for j := 0; j <= 100; j++ {
+				x, y := float64(i-50)*0.3, float64(j-50)*0.3
+				slots[0], slots[1], slots[2] = x, y, math.Hypot(x, y)
+				prog.Eval(slots)
+			}
+		}
+	}
+}
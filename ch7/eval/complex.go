@@ -0,0 +1,138 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"fmt"
+	"math/cmplx"
+)
+
+// A ComplexEnv maps variables to complex values, for EvalComplex.
+type ComplexEnv map[Var]complex128
+
+// ComplexFuncs is the table of functions available to EvalComplex.
+// The number of arguments of each function is that given by Funcs.
+var ComplexFuncs = map[string]func(args []complex128) complex128{
+	"abs":   cfn1(func(z complex128) complex128 { return complex(cmplx.Abs(z), 0) }),
+	"acos":  cfn1(cmplx.Acos),
+	"arg":   cfn1(func(z complex128) complex128 { return complex(cmplx.Phase(z), 0) }),
+	"asin":  cfn1(cmplx.Asin),
+	"atan":  cfn1(cmplx.Atan),
+	"conj":  cfn1(cmplx.Conj),
+	"cos":   cfn1(cmplx.Cos),
+	"exp":   cfn1(cmplx.Exp),
+	"imag":  cfn1(func(z complex128) complex128 { return complex(imag(z), 0) }),
+	"log":   cfn1(cmplx.Log),
+	"log10": cfn1(cmplx.Log10),
+	"pow":   func(args []complex128) complex128 { return cmplx.Pow(args[0], args[1]) },
+	"real":  cfn1(func(z complex128) complex128 { return complex(real(z), 0) }),
+	"sin":   cfn1(cmplx.Sin),
+	"sqrt":  cfn1(cmplx.Sqrt),
+	"tan":   cfn1(cmplx.Tan),
+}
+
+func cfn1(f func(complex128) complex128) func([]complex128) complex128 {
+	return func(args []complex128) complex128 { return f(args[0]) }
+}
+
+// An evalPanic is an error detected by EvalComplex, EvalBig or EvalRat.
+type evalPanic string
+
+// recoverEval converts an evalPanic into an error.
+func recoverEval(err *error) {
+	switch x := recover().(type) {
+	case nil:
+		// no panic
+	case evalPanic:
+		*err = fmt.Errorf("%s", x)
+	default:
+		panic(x)
+	}
+}
+
+// EvalComplex returns the value of e, which should already have
+// been checked by CheckComplex, over the complex numbers.  Imaginary literals such
+// as 2i are permitted.  The ordering comparisons (< <= > >=) are
+// defined only for operands whose imaginary part is zero.
+func EvalComplex(e Expr, env ComplexEnv) (_ complex128, err error) {
+	defer recoverEval(&err)
+	return evalComplex(e, env), nil
+}
+
+func evalComplex(e Expr, env ComplexEnv) complex128 {
+	switch e := e.(type) {
+	case literal:
+		if e.imag {
+			return complex(0, e.val)
+		}
+		return complex(e.val, 0)
+
+	case Var:
+		return env[e]
+
+	case ident:
+		return env[e.name]
+
+	case unary:
+		x := evalComplex(e.x, env)
+		switch e.op {
+		case '+':
+			return x
+		case '-':
+			return -x
+		case '!':
+			return complex(truth(x == 0), 0)
+		}
+
+	case binary:
+		x := evalComplex(e.x, env)
+		switch e.op {
+		case and:
+			return complex(truth(x != 0 && evalComplex(e.y, env) != 0), 0)
+		case or:
+			return complex(truth(x != 0 || evalComplex(e.y, env) != 0), 0)
+		}
+		y := evalComplex(e.y, env)
+		switch e.op {
+		case '+':
+			return x + y
+		case '-':
+			return x - y
+		case '*':
+			return x * y
+		case '/':
+			return x / y
+		case eq:
+			return complex(truth(x == y), 0)
+		case ne:
+			return complex(truth(x != y), 0)
+		}
+		if imag(x) != 0 || imag(y) != 0 {
+			panic(evalPanic(fmt.Sprintf("complex operands of %s are not ordered: %v, %v",
+				opString(e.op), x, y)))
+		}
+		return complex(binary{e.op, num(real(x)), num(real(y)), e.pos}.Eval(nil), 0)
+
+	case conditional:
+		if evalComplex(e.cond, env) != 0 {
+			return evalComplex(e.x, env)
+		}
+		return evalComplex(e.y, env)
+
+	case call:
+		f, ok := ComplexFuncs[e.fn]
+		if !ok {
+			panic(evalPanic(fmt.Sprintf("function %s is not defined for complex numbers", e.fn)))
+		}
+		if g, ok := Funcs[e.fn]; !ok || !g.accepts(len(e.args)) {
+			panic(evalPanic(fmt.Sprintf("call to %s has %d args", e.fn, len(e.args))))
+		}
+		args := make([]complex128, len(e.args))
+		for i, arg := range e.args {
+			args[i] = evalComplex(arg, env)
+		}
+		return f(args)
+	}
+	panic(evalPanic(fmt.Sprintf("cannot evaluate %s over the complex numbers", Format(e))))
+}
@@ -0,0 +1,142 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import "fmt"
+
+// Derive returns the derivative of e with respect to v, simplified.
+// It reports an error if e calls a function whose derivative is not
+// known.  Comparisons and logical operators are piecewise constant,
+// so their derivative is taken to be zero; the derivative of a
+// conditional is the conditional of the derivatives.
+func Derive(e Expr, v Var) (_ Expr, err error) {
+	defer func() {
+		switch x := recover().(type) {
+		case nil:
+			// no panic
+		case derivePanic:
+			err = fmt.Errorf("%s", x)
+		default:
+			panic(x)
+		}
+	}()
+	return Simplify(derive(e, v)), nil
+}
+
+type derivePanic string
+
+func derive(e Expr, v Var) Expr {
+	switch e := e.(type) {
+	case literal:
+		return num(0)
+
+	case Var:
+		if e == v {
+			return num(1)
+		}
+		return num(0)
+
+	case ident:
+		return derive(e.name, v)
+
+	case unary:
+		switch e.op {
+		case '+', '-':
+			return unary{e.op, derive(e.x, v), e.pos}
+		}
+		return num(0) // '!'
+
+	case binary:
+		switch e.op {
+		case '+', '-':
+			return binary{e.op, derive(e.x, v), derive(e.y, v), e.pos}
+		case '*':
+			// (fg)' = f'g + fg'
+			return add(mul(derive(e.x, v), e.y), mul(e.x, derive(e.y, v)))
+		case '/':
+			// (f/g)' = (f'g - fg') / g²
+			return div(
+				sub(mul(derive(e.x, v), e.y), mul(e.x, derive(e.y, v))),
+				mul(e.y, e.y))
+		}
+		return num(0) // comparison or logical operator
+
+	case conditional:
+		return conditional{e.cond, derive(e.x, v), derive(e.y, v), e.pos}
+
+	case call:
+		return deriveCall(e, v)
+	}
+	panic(fmt.Sprintf("unknown Expr: %T", e))
+}
+
+// derivatives gives, for each function of one parameter,
+// its derivative with respect to that parameter.
+var derivatives = map[string]func(u Expr) Expr{
+	"abs":   func(u Expr) Expr { return div(u, fn("abs", u)) },
+	"acos":  func(u Expr) Expr { return neg(div(num(1), fn("sqrt", sub(num(1), mul(u, u))))) },
+	"asin":  func(u Expr) Expr { return div(num(1), fn("sqrt", sub(num(1), mul(u, u)))) },
+	"atan":  func(u Expr) Expr { return div(num(1), add(num(1), mul(u, u))) },
+	"ceil":  func(u Expr) Expr { return num(0) },
+	"cos":   func(u Expr) Expr { return neg(fn("sin", u)) },
+	"exp":   func(u Expr) Expr { return fn("exp", u) },
+	"floor": func(u Expr) Expr { return num(0) },
+	"log":   func(u Expr) Expr { return div(num(1), u) },
+	"log10": func(u Expr) Expr { return div(num(1), mul(u, fn("log", num(10)))) },
+	"sin":   func(u Expr) Expr { return fn("cos", u) },
+	"sqrt":  func(u Expr) Expr { return div(num(1), mul(num(2), fn("sqrt", u))) },
+	"tan":   func(u Expr) Expr { return div(num(1), fn("pow", fn("cos", u), num(2))) },
+}
+
+func deriveCall(c call, v Var) Expr {
+	if d, ok := derivatives[c.fn]; ok && len(c.args) == 1 {
+		u := c.args[0]
+		return mul(d(u), derive(u, v)) // chain rule
+	}
+	switch c.fn {
+	case "pow":
+		f, g := c.args[0], c.args[1]
+		df, dg := derive(f, v), Simplify(derive(g, v))
+		if isLiteral(dg, 0) {
+			// (f^g)' = g f^(g-1) f', for constant g
+			return mul(mul(g, fn("pow", f, sub(g, num(1)))), df)
+		}
+		// (f^g)' = f^g (g' log(f) + g f'/f)
+		return mul(c, add(mul(dg, fn("log", f)), div(mul(g, df), f)))
+
+	case "atan2":
+		// atan2(y, x)' = (x y' - y x') / (x² + y²)
+		y, x := c.args[0], c.args[1]
+		return div(
+			sub(mul(x, derive(y, v)), mul(y, derive(x, v))),
+			add(mul(x, x), mul(y, y)))
+
+	case "hypot":
+		// hypot(a, b)' = (a a' + b b') / hypot(a, b)
+		a, b := c.args[0], c.args[1]
+		return div(add(mul(a, derive(a, v)), mul(b, derive(b, v))), c)
+
+	case "min", "max":
+		// max(a, rest...)' = a >= max(rest...) ? a' : max(rest...)'
+		if len(c.args) == 1 {
+			return derive(c.args[0], v)
+		}
+		var op rune = ge
+		if c.fn == "min" {
+			op = le
+		}
+		a, rest := c.args[0], call{c.fn, c.args[1:], c.pos}
+		return conditional{binary{op, a, rest, c.pos}, derive(a, v), derive(rest, v), c.pos}
+	}
+	panic(derivePanic(fmt.Sprintf("cannot differentiate function %q", c.fn)))
+}
+
+// Helpers for building expressions.
+
+func neg(x Expr) Expr                   { return unary{op: '-', x: x} }
+func add(x, y Expr) Expr                { return binary{op: '+', x: x, y: y} }
+func sub(x, y Expr) Expr                { return binary{op: '-', x: x, y: y} }
+func mul(x, y Expr) Expr                { return binary{op: '*', x: x, y: y} }
+func div(x, y Expr) Expr                { return binary{op: '/', x: x, y: y} }
+func fn(name string, args ...Expr) Expr { return call{fn: name, args: args} }
@@ -0,0 +1,157 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	for _, test := range []struct{ input, want string }{
+		{"x * 1 + 0", "x"},
+		{"0 * x + 1 * y", "y"},
+		{"x - -y", "(x + y)"},
+		{"x + -y", "(x - y)"},
+		{"0 - x", "(-x)"},
+		{"-(-x)", "x"},
+		{"2 * 3 + x / 1", "(6 + x)"},
+		{"pow(x, 1) + pow(y, 0)", "(x + 1)"},
+		{"sqrt(16) * x", "(4 * x)"},
+		{"1 < 2 ? x : y", "x"},
+		{"1 < 2 && x > 0", "((1 < 2) && (x > 0))"},
+		{"foo(2 * 3)", "foo(6)"},
+	} {
+		expr, err := Parse(test.input)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got := Format(Simplify(expr)); got != test.want {
+			t.Errorf("Simplify(%s) = %s, want %s", test.input, got, test.want)
+		}
+	}
+}
+
+// TestSimplifyNonFinite checks that constants whose values are NaN or
+// infinite are not folded, since Format cannot write them as literals.
+func TestSimplifyNonFinite(t *testing.T) {
+	same := func(x, y float64) bool { return x == y || math.IsNaN(x) && math.IsNaN(y) }
+	env := Env{"x": 2}
+	for _, input := range []string{
+		"sqrt(-1)",
+		"1 / 0",
+		"-1 / 0 + x",
+		"1 / 0 * x",
+		"log(0) - x",
+		"pow(2, 2000) * x",
+	} {
+		expr, err := Parse(input)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		s := Format(Simplify(expr))
+		round, err := Parse(s)
+		if err != nil {
+			t.Errorf("Parse(Format(Simplify(%s))) = Parse(%s): %v", input, s, err)
+			continue
+		}
+		if got, want := round.Eval(env), expr.Eval(env); !same(got, want) {
+			t.Errorf("Simplify(%s) = %s = %g, want %g", input, s, got, want)
+		}
+	}
+
+	// Derive simplifies its result too.
+	expr, _ := Parse("1 / 0 * x")
+	d, err := Derive(expr, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	round, err := Parse(Format(d))
+	if err != nil {
+		t.Fatalf("Parse(%s): %v", Format(d), err)
+	}
+	if got, want := round.Eval(env), d.Eval(env); !same(got, want) {
+		t.Errorf("Derive(1 / 0 * x) = %s = %g, want %g", Format(d), got, want)
+	}
+}
+
+func TestDerive(t *testing.T) {
+	for _, test := range []struct{ input, want string }{
+		{"3", "0"},
+		{"y", "0"},
+		{"x", "1"},
+		{"3 * x + y", "3"},
+		{"x * x", "(x + x)"},
+		{"pow(x, 3)", "(3 * pow(x, 2))"},
+		{"sin(2 * x)", "(cos((2 * x)) * 2)"},
+		{"sqrt(x)", "(1 / (2 * sqrt(x)))"},
+		{"1 / x", "(-1 / (x * x))"},
+		{"x > 0 ? x : -x", "((x > 0) ? 1 : -1)"},
+	} {
+		expr, err := Parse(test.input)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		d, err := Derive(expr, "x")
+		if err != nil {
+			t.Errorf("Derive(%s): %v", test.input, err)
+			continue
+		}
+		if got := Format(d); got != test.want {
+			t.Errorf("Derive(%s) = %s, want %s", test.input, got, test.want)
+		}
+	}
+}
+
+// TestDeriveNumeric compares each symbolic derivative
+// with a central-difference approximation.
+func TestDeriveNumeric(t *testing.T) {
+	for _, input := range []string{
+		"x * sin(x) / (1 + x * x)",
+		"pow(x, y) + pow(2, x)",
+		"exp(-x) * cos(3 * x) - log(x) + log10(x)",
+		"sqrt(x * x + y) + tan(x) + atan(x)",
+		"asin(x / 4) + acos(x / 5) + abs(x - 3)",
+		"atan2(y, x) + hypot(x, y)",
+		"max(x, y, 1) + min(x * x, 2)",
+	} {
+		expr, err := Parse(input)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		d, err := Derive(expr, "x")
+		if err != nil {
+			t.Errorf("Derive(%s): %v", input, err)
+			continue
+		}
+		for _, x := range []float64{0.3, 1.7, 2.9} {
+			const h = 1e-6
+			env := Env{"x": x, "y": 1.3}
+			lo, hi := Env{"x": x - h, "y": 1.3}, Env{"x": x + h, "y": 1.3}
+			want := (expr.Eval(hi) - expr.Eval(lo)) / (2 * h)
+			got := d.Eval(env)
+			if math.Abs(got-want) > 1e-5*math.Max(1, math.Abs(want)) {
+				t.Errorf("d/dx %s at x=%g: got %g, want %g (%s)",
+					input, x, got, want, Format(d))
+			}
+		}
+	}
+}
+
+func TestDeriveError(t *testing.T) {
+	Funcs["f"] = fn1(math.Cbrt)
+	defer delete(Funcs, "f")
+	expr, err := Parse("f(x)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Derive(expr, "x")
+	if want := `cannot differentiate function "f"`; err == nil || err.Error() != want {
+		t.Errorf("Derive(f(x)) returned error %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import "math"
+
+// Equal reports whether x and y are structurally equal expressions.
+// Positions and the source text of literals are ignored, and a Var
+// is equal to a reference to the same variable produced by Parse.
+func Equal(x, y Expr) bool {
+	if v, ok := x.(ident); ok {
+		x = v.name
+	}
+	if v, ok := y.(ident); ok {
+		y = v.name
+	}
+	switch x := x.(type) {
+	case literal:
+		y, ok := y.(literal)
+		return ok && x.imag == y.imag &&
+			math.Float64bits(x.val) == math.Float64bits(y.val)
+	case Var:
+		y, ok := y.(Var)
+		return ok && x == y
+	case unary:
+		y, ok := y.(unary)
+		return ok && x.op == y.op && Equal(x.x, y.x)
+	case binary:
+		y, ok := y.(binary)
+		return ok && x.op == y.op && Equal(x.x, y.x) && Equal(x.y, y.y)
+	case conditional:
+		y, ok := y.(conditional)
+		return ok && Equal(x.cond, y.cond) && Equal(x.x, y.x) && Equal(x.y, y.y)
+	case call:
+		y, ok := y.(call)
+		if !ok || x.fn != y.fn || len(x.args) != len(y.args) {
+			return false
+		}
+		for i := range x.args {
+			if !Equal(x.args[i], y.args[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
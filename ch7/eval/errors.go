@@ -0,0 +1,95 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/scanner"
+)
+
+// An Error describes a problem in an expression and where it occurs.
+type Error struct {
+	Pos scanner.Position // Line and Column are 1-based; zero if unknown
+	Msg string
+}
+
+func (e *Error) Error() string {
+	if !e.Pos.IsValid() {
+		return e.Msg
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Pos.Line, e.Pos.Column, e.Msg)
+}
+
+// Snippet returns the line of input containing the error, followed
+// by a line with a caret beneath the error's column, e.g.,
+//
+//	sqrt(x, y)
+//	^
+//
+// It returns the empty string if the position is unknown.
+func (e *Error) Snippet(input string) string {
+	lines := strings.Split(input, "\n")
+	if !e.Pos.IsValid() || e.Pos.Line > len(lines) {
+		return ""
+	}
+	line := lines[e.Pos.Line-1]
+	// Indent the caret using the same whitespace as the line.
+	var indent strings.Builder
+	for i, r := range []rune(line) {
+		if i >= e.Pos.Column-1 {
+			break
+		}
+		if r == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	return line + "\n" + indent.String() + "^"
+}
+
+// Explain describes err, an error from Parse or CheckAll of input,
+// adding beneath each *Error its Snippet of input.  Other errors
+// are described by their Error method alone.
+func Explain(input string, err error) string {
+	var list ErrorList
+	switch err := err.(type) {
+	case *Error:
+		list = ErrorList{err}
+	case ErrorList:
+		list = err
+	default:
+		return err.Error()
+	}
+	var lines []string
+	for _, e := range list {
+		lines = append(lines, e.Error())
+		if snippet := e.Snippet(input); snippet != "" {
+			lines = append(lines, snippet)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// An ErrorList is a list of errors, as returned by CheckAll.
+type ErrorList []*Error
+
+func (list ErrorList) Error() string {
+	switch len(list) {
+	case 0:
+		return "no errors"
+	case 1:
+		return list[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", list[0], len(list)-1)
+}
+
+// Sort sorts the list by position.
+func (list ErrorList) Sort() {
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Pos.Offset < list[j].Pos.Offset
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import "testing"
+
+func TestCheckAll(t *testing.T) {
+	input := "sqrt(x, 1) + lg(y) * z"
+	expr, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CheckAll(expr, map[Var]bool{"x": true, "y": true})
+	list, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("CheckAll returned %v, want ErrorList", err)
+	}
+	want := []string{
+		"1:1: call to sqrt has 2 args, want 1",
+		`1:14: unknown function "lg"`,
+		"1:22: undefined variable: z",
+	}
+	if len(list) != len(want) {
+		t.Fatalf("CheckAll returned %d errors (%v), want %d", len(list), list, len(want))
+	}
+	for i, e := range list {
+		if e.Error() != want[i] {
+			t.Errorf("error %d = %q, want %q", i, e, want[i])
+		}
+	}
+	if got, want := list.Error(), want[0]+" (and 2 more errors)"; got != want {
+		t.Errorf("ErrorList.Error() = %q, want %q", got, want)
+	}
+	if err := CheckAll(expr, nil); err == nil {
+		t.Errorf("CheckAll with no allowed set returned no error")
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	for _, test := range []struct{ input, want string }{
+		{"1 + (2 * 3", "1 + (2 * 3\n          ^"},
+		{"x +\n\ty )", "\ty )\n\t  ^"},
+		{"sin(x) +", "sin(x) +\n        ^"},
+	} {
+		_, err := Parse(test.input)
+		e, ok := err.(*Error)
+		if !ok {
+			t.Errorf("Parse(%q) returned %v, want *Error", test.input, err)
+			continue
+		}
+		if got := e.Snippet(test.input); got != test.want {
+			t.Errorf("Parse(%q) error snippet:\n%s\nwant:\n%s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	input := "sqrt(x, y) + z"
+	expr, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CheckAll(expr, map[Var]bool{"x": true, "y": true})
+	want := "1:1: call to sqrt has 2 args, want 1\n" +
+		"sqrt(x, y) + z\n" +
+		"^\n" +
+		"1:14: undefined variable: z\n" +
+		"sqrt(x, y) + z\n" +
+		"             ^"
+	if got := Explain(input, err); got != want {
+		t.Errorf("Explain:\n%s\nwant:\n%s", got, want)
+	}
+	if got := Explain(input, &LimitError{"nodes", 10}); got != "expression too large (maximum 10 nodes)" {
+		t.Errorf("Explain(LimitError) = %q", got)
+	}
+}
@@ -24,7 +24,14 @@ func (v Var) Eval(env Env) float64 {
 }
 
 func (l literal) Eval(_ Env) float64 {
-	return float64(l)
+	if l.imag {
+		return math.NaN() // see EvalComplex
+	}
+	return l.val
+}
+
+func (id ident) Eval(env Env) float64 {
+	return env[id.name]
 }
 
 //!-Eval1
@@ -37,6 +44,8 @@ func (u unary) Eval(env Env) float64 {
 		return +u.x.Eval(env)
 	case '-':
 		return -u.x.Eval(env)
+	case '!':
+		return truth(u.x.Eval(env) == 0)
 	}
 	panic(fmt.Sprintf("unsupported unary operator: %q", u.op))
 }
@@ -55,20 +64,51 @@ func (b binary) Eval(env Env) float64 {
 		return b.x.Eval(env) * b.y.Eval(env)
 	case '/':
 		return b.x.Eval(env) / b.y.Eval(env)
+	case '<':
+		return truth(b.x.Eval(env) < b.y.Eval(env))
+	case '>':
+		return truth(b.x.Eval(env) > b.y.Eval(env))
+	case le:
+		return truth(b.x.Eval(env) <= b.y.Eval(env))
+	case ge:
+		return truth(b.x.Eval(env) >= b.y.Eval(env))
+	case eq:
+		return truth(b.x.Eval(env) == b.y.Eval(env))
+	case ne:
+		return truth(b.x.Eval(env) != b.y.Eval(env))
+	case and:
+		return truth(b.x.Eval(env) != 0 && b.y.Eval(env) != 0)
+	case or:
+		return truth(b.x.Eval(env) != 0 || b.y.Eval(env) != 0)
+	}
+	panic(fmt.Sprintf("unsupported binary operator: %s", opString(b.op)))
+}
+
+func (c conditional) Eval(env Env) float64 {
+	if c.cond.Eval(env) != 0 {
+		return c.x.Eval(env)
 	}
-	panic(fmt.Sprintf("unsupported binary operator: %q", b.op))
+	return c.y.Eval(env)
 }
 
 func (c call) Eval(env Env) float64 {
-	switch c.fn {
-	case "pow":
-		return math.Pow(c.args[0].Eval(env), c.args[1].Eval(env))
-	case "sin":
-		return math.Sin(c.args[0].Eval(env))
-	case "sqrt":
-		return math.Sqrt(c.args[0].Eval(env))
+	f, ok := Funcs[c.fn]
+	if !ok {
+		panic(fmt.Sprintf("unsupported function call: %s", c.fn))
+	}
+	args := make([]float64, len(c.args))
+	for i, arg := range c.args {
+		args[i] = arg.Eval(env)
 	}
-	panic(fmt.Sprintf("unsupported function call: %s", c.fn))
+	return f.Fn(args)
 }
 
 //!-Eval2
+
+// truth converts a boolean to the number 1 (true) or 0 (false).
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
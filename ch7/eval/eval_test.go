@@ -9,27 +9,42 @@ import (
 	"testing"
 )
 
+// evalTests are the cases of TestEval, which other tests share.
+
 //!+Eval
+var evalTests = []struct {
+	expr string
+	env  Env
+	want string
+}{
+	{"sqrt(A / pi)", Env{"A": 87616, "pi": math.Pi}, "167"},
+	{"pow(x, 3) + pow(y, 3)", Env{"x": 12, "y": 1}, "1729"},
+	{"pow(x, 3) + pow(y, 3)", Env{"x": 9, "y": 10}, "1729"},
+	{"5 / 9 * (F - 32)", Env{"F": -40}, "-40"},
+	{"5 / 9 * (F - 32)", Env{"F": 32}, "0"},
+	{"5 / 9 * (F - 32)", Env{"F": 212}, "100"},
+	//!-Eval
+	// additional tests that don't appear in the book
+	{"-1 + -x", Env{"x": 1}, "-2"},
+	{"-1 - x", Env{"x": 1}, "-2"},
+	{"x > 0 ? sqrt(x) : 0", Env{"x": 16}, "4"},
+	{"x > 0 ? sqrt(x) : 0", Env{"x": -16}, "0"},
+	{"r < 5 && y > 1", Env{"r": 4, "y": 2}, "1"},
+	{"r < 5 && y > 1", Env{"r": 6, "y": 2}, "0"},
+	{"x <= 1 || x >= 3", Env{"x": 2}, "0"},
+	{"!(x == 1) && x != 2", Env{"x": 3}, "1"},
+	{"x < 0 ? -1 : x == 0 ? 0 : 1", Env{"x": 0}, "0"},
+	{"1 + 2 * 3 < 2 * 4", nil, "1"},
+	{"pow(cos(x), 2) + pow(sin(x), 2)", Env{"x": 0.7}, "1"},
+	{"min(x, 3, y) + max(x)", Env{"x": 5, "y": -2}, "3"},
+	{"atan2(1, 1) * 4 / log(exp(1))", nil, "3.14159"},
+	{"abs(-x) + hypot(3, 4)", Env{"x": 2}, "7"},
+	//!+Eval
+}
+
 func TestEval(t *testing.T) {
-	tests := []struct {
-		expr string
-		env  Env
-		want string
-	}{
-		{"sqrt(A / pi)", Env{"A": 87616, "pi": math.Pi}, "167"},
-		{"pow(x, 3) + pow(y, 3)", Env{"x": 12, "y": 1}, "1729"},
-		{"pow(x, 3) + pow(y, 3)", Env{"x": 9, "y": 10}, "1729"},
-		{"5 / 9 * (F - 32)", Env{"F": -40}, "-40"},
-		{"5 / 9 * (F - 32)", Env{"F": 32}, "0"},
-		{"5 / 9 * (F - 32)", Env{"F": 212}, "100"},
-		//!-Eval
-		// additional tests that don't appear in the book
-		{"-1 + -x", Env{"x": 1}, "-2"},
-		{"-1 - x", Env{"x": 1}, "-2"},
-		//!+Eval
-	}
 	var prevExpr string
-	for _, test := range tests {
+	for _, test := range evalTests {
 		// Print expr only when it changes.
 		if test.expr != prevExpr {
 			fmt.Printf("\n%s\n", test.expr)
@@ -77,12 +92,22 @@ pow(x, 3) + pow(y, 3)
 
 func TestErrors(t *testing.T) {
 	for _, test := range []struct{ expr, wantErr string }{
-		{"x % 2", "unexpected '%'"},
-		{"math.Pi", "unexpected '.'"},
-		{"!true", "unexpected '!'"},
-		{`"hello"`, "unexpected '\"'"},
-		{"log(10)", `unknown function "log"`},
-		{"sqrt(1, 2)", "call to sqrt has 2 args, want 1"},
+		{"x % 2", "1:3: unexpected '%'"},
+		{"math.Pi", "1:5: unexpected '.'"},
+		{"!true", "1:1: operand of ! is numeric, want boolean"},
+		{`"hello"`, "1:1: unexpected '\"'"},
+		{"lg(10)", `1:1: unknown function "lg"`},
+		{"sqrt(1, 2)", "1:1: call to sqrt has 2 args, want 1"},
+		{"min()", "1:1: call to min has 0 args, want at least 1"},
+		{"x & y", "1:3: unexpected '&'"},
+		{"x = 1", "1:3: unexpected '='"},
+		{"x > 0 ? 1", `1:10: got end of file, want ':'`},
+		{"x && y > 0", "1:3: operand of && is numeric, want boolean"},
+		{"(x > 0) + 1", "1:9: operand of + is boolean, want numeric"},
+		{"-(x < y)", "1:1: operand of - is boolean, want numeric"},
+		{"x ? 1 : 0", "1:3: condition of ?: is numeric, want boolean"},
+		{"x > 0 ? 1 : y < 0", "1:7: mismatched operands of ?: (numeric and boolean)"},
+		{"sqrt(x >= 0)", "1:1: argument of sqrt is boolean, want numeric"},
 	} {
 		expr, err := Parse(test.expr)
 		if err == nil {
@@ -102,12 +127,32 @@ func TestErrors(t *testing.T) {
 
 /*
 //!+errors
-x % 2               unexpected '%'
-math.Pi             unexpected '.'
-!true               unexpected '!'
-"hello"             unexpected '"'
+x % 2               1:3: unexpected '%'
+math.Pi             1:5: unexpected '.'
+!true               1:1: operand of ! is numeric, want boolean
+"hello"             1:1: unexpected '"'
 
-log(10)             unknown function "log"
-sqrt(1, 2)          call to sqrt has 2 args, want 1
+lg(10)              1:1: unknown function "lg"
+sqrt(1, 2)          1:1: call to sqrt has 2 args, want 1
 //!-errors
 */
+
+func TestRegister(t *testing.T) {
+	Funcs["clamp"] = Func{Params: 3, Fn: func(args []float64) float64 {
+		return math.Max(args[1], math.Min(args[0], args[2]))
+	}}
+	defer delete(Funcs, "clamp")
+
+	expr, err := Parse("clamp(x, 0, 1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expr.Check(map[Var]bool{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct{ x, want float64 }{{-1, 0}, {0.5, 0.5}, {2, 1}} {
+		if got := expr.Eval(Env{"x": test.x}); got != test.want {
+			t.Errorf("clamp(%g, 0, 1) = %g, want %g", test.x, got, test.want)
+		}
+	}
+}
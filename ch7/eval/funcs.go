@@ -0,0 +1,79 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import "math"
+
+// A Func describes a function that may be called from an expression.
+type Func struct {
+	Params   int  // number of parameters (the minimum, if Variadic)
+	Variadic bool // whether the function accepts extra arguments
+	Fn       func(args []float64) float64
+}
+
+// Funcs is the table of functions known to Check and Eval, indexed
+// by name.  Clients may add their own functions to it, typically
+// during initialization, before any expression is checked.
+//
+// The functions arg, conj, real and imag are meaningful mainly for
+// complex numbers (see EvalComplex); here they treat their argument
+// as a complex number with no imaginary part.
+var Funcs = map[string]Func{
+	"abs":   fn1(math.Abs),
+	"acos":  fn1(math.Acos),
+	"arg":   fn1(func(x float64) float64 { return math.Atan2(0, x) }),
+	"asin":  fn1(math.Asin),
+	"atan":  fn1(math.Atan),
+	"atan2": fn2(math.Atan2),
+	"ceil":  fn1(math.Ceil),
+	"conj":  fn1(func(x float64) float64 { return x }),
+	"cos":   fn1(math.Cos),
+	"exp":   fn1(math.Exp),
+	"floor": fn1(math.Floor),
+	"hypot": fn2(math.Hypot),
+	"imag":  fn1(func(x float64) float64 { return 0 }),
+	"log":   fn1(math.Log),
+	"log10": fn1(math.Log10),
+	"max":   {Params: 1, Variadic: true, Fn: fold(math.Max)},
+	"min":   {Params: 1, Variadic: true, Fn: fold(math.Min)},
+	"pow":   fn2(math.Pow),
+	"real":  fn1(func(x float64) float64 { return x }),
+	"sin":   fn1(math.Sin),
+	"sqrt":  fn1(math.Sqrt),
+	"tan":   fn1(math.Tan),
+}
+
+// accepts reports whether f may be called with n arguments.
+func (f Func) accepts(n int) bool {
+	if f.Variadic {
+		return n >= f.Params
+	}
+	return n == f.Params
+}
+
+// fn1 returns a Func for a function of one parameter.
+func fn1(f func(float64) float64) Func {
+	return Func{Params: 1, Fn: func(args []float64) float64 {
+		return f(args[0])
+	}}
+}
+
+// fn2 returns a Func for a function of two parameters.
+func fn2(f func(x, y float64) float64) Func {
+	return Func{Params: 2, Fn: func(args []float64) float64 {
+		return f(args[0], args[1])
+	}}
+}
+
+// fold returns the variadic function that combines its
+// arguments from left to right using f.
+func fold(f func(x, y float64) float64) func([]float64) float64 {
+	return func(args []float64) float64 {
+		z := args[0]
+		for _, x := range args[1:] {
+			z = f(z, x)
+		}
+		return z
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestFormatGo generates a Go function for each of the evalTests,
+// type-checks the resulting program, then runs it (if the go tool is
+// available) and compares its output with Eval.
+func TestFormatGo(t *testing.T) {
+	var src, calls, want bytes.Buffer
+	src.WriteString("package main\n\nimport (\n\t\"fmt\"\n\t\"math\"\n)\n\nvar _ = math.Pi\n\n")
+	for i, test := range evalTests {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var params []Var
+		var args []string
+		for v := range test.env {
+			params = append(params, v)
+		}
+		sort.Slice(params, func(i, j int) bool { return params[i] < params[j] })
+		for _, v := range params {
+			args = append(args, fmt.Sprint(test.env[v]))
+		}
+		fn, err := FormatGo(expr, fmt.Sprintf("f%d", i), params)
+		if err != nil {
+			t.Errorf("FormatGo(%s): %v", test.expr, err)
+			continue
+		}
+		src.WriteString(fn)
+		fmt.Fprintf(&calls, "\tfmt.Printf(\"%%.6g\\n\", f%d(%s))\n", i, strings.Join(args, ", "))
+		fmt.Fprintf(&want, "%.6g\n", expr.Eval(test.env))
+	}
+	fmt.Fprintf(&src, "\nfunc main() {\n%s}\n", &calls)
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "gen.go", src.Bytes(), 0)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, &src)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("main", fset, []*ast.File{f}, nil); err != nil {
+		t.Fatalf("%v\n%s", err, &src)
+	}
+
+	gotool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found; not running generated code")
+	}
+	dir, err := ioutil.TempDir("", "eval")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "gen.go")
+	if err := ioutil.WriteFile(filename, src.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(gotool, "run", filename).CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run: %v\n%s", err, out)
+	}
+	if string(out) != want.String() {
+		t.Errorf("generated code printed:\n%s\nEval yielded:\n%s", out, &want)
+	}
+}
+
+func TestFormatGoErrors(t *testing.T) {
+	for _, test := range []struct {
+		expr    string
+		params  []Var
+		wantErr string
+	}{
+		{"x + y", []Var{"x"}, "1:5: undefined variable: y"},
+		{"2i * x", []Var{"x"}, "1:1: imaginary literal 2i is not a real number (use EvalComplex)"},
+		{"clamp(x)", []Var{"x"}, `1:1: unknown function "clamp"`},
+		{"math + 1", []Var{"math"}, "parameter math is not a valid Go name"},
+	} {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = FormatGo(expr, "f", test.params)
+		if err == nil || err.Error() != test.wantErr {
+			t.Errorf("FormatGo(%s) = %v, want %s", test.expr, err, test.wantErr)
+		}
+	}
+}
+
+func TestFormatGoConstants(t *testing.T) {
+	for _, test := range []struct{ expr, want string }{
+		{"5 / 9 * (F - 32)", "0.5555555555555556 * (F - 32)"},
+		{"x / (1 - 1)", "x / math.Copysign(0, 1)"},
+		{"x - -1", "x - -1"},
+		{"-(-x)", "-(-x)"},
+		{"x - (x - 1)", "x - (x - 1)"},
+		{"x > 0 ? 1 : 0", "func() float64 { if x > 0 { return 1 }; return 0 }()"},
+	} {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		params := []Var{"x"}
+		if strings.Contains(test.expr, "F") {
+			params = []Var{"F"}
+		}
+		src, err := FormatGo(expr, "f", params)
+		if err != nil {
+			t.Errorf("FormatGo(%s): %v", test.expr, err)
+			continue
+		}
+		if !strings.Contains(src, "\treturn "+test.want+"\n") {
+			t.Errorf("FormatGo(%s) =\n%s\nwant return %s", test.expr, src, test.want)
+		}
+	}
+}
+
+func TestFormatLaTeX(t *testing.T) {
+	for _, test := range []struct{ expr, latex, mathml string }{
+		{"sqrt(A / pi)", `\sqrt{\frac{A}{\pi}}`,
+			"<msqrt><mfrac><mi>A</mi><mi>π</mi></mfrac></msqrt>"},
+		{"5 / 9 * (F - 32)", `\frac{5}{9} \cdot \left(F - 32\right)`,
+			"<mrow><mfrac><mn>5</mn><mn>9</mn></mfrac><mo>⋅</mo><mrow><mo>(</mo><mrow><mi>F</mi><mo>-</mo><mn>32</mn></mrow><mo>)</mo></mrow></mrow>"},
+		{"pow(x, 3) + pow(-y, 2)", `x^{3} + \left(-y\right)^{2}`,
+			"<mrow><msup><mi>x</mi><mn>3</mn></msup><mo>+</mo><msup><mrow><mo>(</mo><mrow><mo>-</mo><mi>y</mi></mrow><mo>)</mo></mrow><mn>2</mn></msup></mrow>"},
+		{"a + (b - c) - (d + e)", `a + b - c - \left(d + e\right)`, ""},
+		{"-1 - -x", `-1 - \left(-x\right)`, ""},
+		{"(x + 1) * (y * z)", `\left(x + 1\right) \cdot y \cdot z`, ""},
+		{"!(x == 1) && x_max <= 1.5e-7", `\lnot \left(x = 1\right) \land \mathit{x\_max} \le 1.5 \times 10^{-7}`,
+			"<mrow><mrow><mo>¬</mo><mrow><mo>(</mo><mrow><mi>x</mi><mo>=</mo><mn>1</mn></mrow><mo>)</mo></mrow></mrow><mo>∧</mo><mrow><mi>x_max</mi><mo>≤</mo><mrow><mn>1.5</mn><mo>×</mo><msup><mn>10</mn><mn>-7</mn></msup></mrow></mrow></mrow>"},
+		{"x > 0 ? abs(x) : exp(x)", `\begin{cases} \left|x\right| & \text{if } x > 0 \\ e^{x} & \text{otherwise} \end{cases}`, ""},
+		{"atan2(y, x) + log(x)", `\operatorname{atan2}\left(y, x\right) + \ln\left(x\right)`,
+			"<mrow><mrow><mi>atan2</mi><mo>&#x2061;</mo><mrow><mo>(</mo><mi>y</mi><mo>,</mo><mi>x</mi><mo>)</mo></mrow></mrow><mo>+</mo><mrow><mi>ln</mi><mo>&#x2061;</mo><mrow><mo>(</mo><mi>x</mi><mo>)</mo></mrow></mrow></mrow>"},
+	} {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := FormatLaTeX(expr); got != test.latex {
+			t.Errorf("FormatLaTeX(%s) = %s, want %s", test.expr, got, test.latex)
+		}
+		if test.mathml == "" {
+			continue
+		}
+		want := `<math xmlns="http://www.w3.org/1998/Math/MathML">` + test.mathml + "</math>"
+		if got := FormatMathML(expr); got != want {
+			t.Errorf("FormatMathML(%s) =\n%s\nwant\n%s", test.expr, got, want)
+		}
+	}
+}
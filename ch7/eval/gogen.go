@@ -0,0 +1,241 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// goFuncs maps each function of one or two parameters to its
+// equivalent in package math.
+var goFuncs = map[string]string{
+	"abs": "math.Abs", "acos": "math.Acos", "asin": "math.Asin",
+	"atan": "math.Atan", "atan2": "math.Atan2", "ceil": "math.Ceil",
+	"cos": "math.Cos", "exp": "math.Exp", "floor": "math.Floor",
+	"hypot": "math.Hypot", "log": "math.Log", "log10": "math.Log10",
+	"pow": "math.Pow", "sin": "math.Sin", "sqrt": "math.Sqrt",
+	"tan": "math.Tan",
+}
+
+// FormatGo returns the source of a Go function with the given name
+// that computes e as a function of params, e.g.,
+//
+//	func f(x, y float64) float64 {
+//		return math.Sin(x) * y
+//	}
+//
+// Subexpressions without variables are evaluated in advance.  The
+// source may refer to package math.  It reports an error if e is
+// ill-formed, refers to a variable not in params, or calls a function
+// with no Go equivalent.
+func FormatGo(e Expr, name string, params []Var) (string, error) {
+	allowed := make(map[Var]bool)
+	var names []string
+	for _, v := range params {
+		if !token.IsIdentifier(string(v)) || v == "math" {
+			return "", fmt.Errorf("parameter %s is not a valid Go name", v)
+		}
+		allowed[v] = true
+		names = append(names, string(v))
+	}
+	if err := CheckAll(e, allowed); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if names != nil {
+		names[len(names)-1] += " float64"
+	}
+	fmt.Fprintf(&buf, "func %s(%s) float64 {\n\treturn ", name, strings.Join(names, ", "))
+	if err := writeGo(&buf, e, numeric); err != nil {
+		return "", err
+	}
+	buf.WriteString("\n}\n")
+	return buf.String(), nil
+}
+
+// writeGo writes e as a Go expression of type float64 (if want is
+// numeric) or bool.  Comparison and logical operators in a numeric
+// context are converted to 1 or 0, as with Eval.
+func writeGo(buf *bytes.Buffer, e Expr, want kind) error {
+	if kindOf(e) != want {
+		// A boolean in a numeric context.
+		buf.WriteString("func() float64 { if ")
+		if err := writeGo(buf, e, boolean); err != nil {
+			return err
+		}
+		buf.WriteString(" { return 1 }; return 0 }()")
+		return nil
+	}
+	// Go evaluates constant expressions exactly, with integer
+	// division for 5 / 9, so fold them as Eval would.
+	e = foldConstant(e)
+	switch e := e.(type) {
+	case literal:
+		switch {
+		case e.imag:
+			return fmt.Errorf("imaginary literal %s has no Go equivalent", Format(e))
+		case math.IsNaN(e.val):
+			buf.WriteString("math.NaN()")
+		case math.IsInf(e.val, 0):
+			fmt.Fprintf(buf, "math.Inf(%d)", int(math.Copysign(1, e.val)))
+		case e.val == 0 && math.Signbit(e.val):
+			buf.WriteString("math.Copysign(0, -1)") // Go constants have no -0
+		default:
+			buf.WriteString(strconv.FormatFloat(e.val, 'g', -1, 64))
+		}
+
+	case Var:
+		buf.WriteString(string(e))
+
+	case ident:
+		buf.WriteString(string(e.name))
+
+	case unary:
+		buf.WriteString(string(e.op))
+		x := foldConstant(e.x)
+		return writeGoOperand(buf, e.x, want, prec(x) < prec(e) || isUnary(x))
+
+	case binary:
+		operand := numeric
+		if e.op == and || e.op == or {
+			operand = boolean
+		}
+		p := precedence(e.op)
+		if err := writeGoOperand(buf, e.x, operand, prec(foldConstant(e.x)) < p); err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, " %s ", opString(e.op))
+		if l, ok := foldConstant(e.y).(literal); ok && e.op == '/' && l.val == 0 {
+			// Go rejects division by a constant zero.
+			fmt.Fprintf(buf, "math.Copysign(0, %g)", math.Copysign(1, l.val))
+			return nil
+		}
+		return writeGoOperand(buf, e.y, operand, prec(foldConstant(e.y)) <= p)
+
+	case conditional:
+		fmt.Fprintf(buf, "func() %s { if ", goType(want))
+		if err := writeGo(buf, e.cond, boolean); err != nil {
+			return err
+		}
+		buf.WriteString(" { return ")
+		if err := writeGo(buf, e.x, want); err != nil {
+			return err
+		}
+		buf.WriteString(" }; return ")
+		if err := writeGo(buf, e.y, want); err != nil {
+			return err
+		}
+		buf.WriteString(" }()")
+
+	case call:
+		return writeGoCall(buf, e)
+
+	default:
+		return fmt.Errorf("unknown Expr: %T", e)
+	}
+	return nil
+}
+
+// writeGoOperand writes an operand, in parentheses if paren.
+func writeGoOperand(buf *bytes.Buffer, e Expr, want kind, paren bool) error {
+	if paren {
+		buf.WriteByte('(')
+	}
+	if err := writeGo(buf, e, want); err != nil {
+		return err
+	}
+	if paren {
+		buf.WriteByte(')')
+	}
+	return nil
+}
+
+func writeGoCall(buf *bytes.Buffer, c call) error {
+	switch c.fn {
+	case "min", "max":
+		// min(a, b, c) = math.Min(math.Min(a, b), c)
+		fn := "math.Min"
+		if c.fn == "max" {
+			fn = "math.Max"
+		}
+		buf.WriteString(strings.Repeat(fn+"(", len(c.args)-1))
+		for i, arg := range c.args {
+			if err := writeGo(buf, arg, numeric); err != nil {
+				return err
+			}
+			if i > 0 {
+				buf.WriteByte(')')
+			}
+			if i < len(c.args)-1 {
+				buf.WriteString(", ")
+			}
+		}
+		return nil
+	case "real", "conj":
+		return writeGoOperand(buf, c.args[0], numeric, true)
+	case "imag":
+		buf.WriteString("0")
+		return nil
+	case "arg":
+		buf.WriteString("math.Atan2(0, ")
+		if err := writeGo(buf, c.args[0], numeric); err != nil {
+			return err
+		}
+		buf.WriteByte(')')
+		return nil
+	}
+	fn, ok := goFuncs[c.fn]
+	if !ok {
+		return fmt.Errorf("function %s has no Go equivalent", c.fn)
+	}
+	fmt.Fprintf(buf, "%s(", fn)
+	for i, arg := range c.args {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if err := writeGo(buf, arg, numeric); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(')')
+	return nil
+}
+
+func goType(k kind) string {
+	if k == boolean {
+		return "bool"
+	}
+	return "float64"
+}
+
+// prec returns the precedence of e as an operand: the precedence of
+// its operator if it is binary, higher for a unary operator, and
+// higher still for an atom such as a variable or call.  A conditional
+// has the lowest precedence.
+func prec(e Expr) int {
+	switch e := e.(type) {
+	case binary:
+		return precedence(e.op)
+	case unary:
+		return precedence('*') + 1
+	case conditional:
+		return 0
+	case literal:
+		if e.val < 0 {
+			return precedence('*') + 1 // like a unary minus
+		}
+	}
+	return precedence('*') + 2
+}
+
+// isUnary reports whether e would be written with a leading
+// sign, and so needs parentheses as the operand of a unary operator.
+func isUnary(e Expr) bool {
+	return prec(e) == precedence('*')+1
+}
@@ -0,0 +1,221 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// An Expr is encoded in JSON as an object whose "kind" field
+// identifies the type of node, e.g., sin(x + 1) is encoded as
+//
+//	{"kind": "call", "fn": "sin", "args": [
+//		{"kind": "binary", "op": "+",
+//		 "x": {"kind": "var", "name": "x"},
+//		 "y": {"kind": "literal", "value": "1"}}]}
+//
+// The value of a literal is a string so that its source text,
+// and non-finite values such as NaN, are preserved.
+type jsonExpr struct {
+	Kind  string      `json:"kind"` // literal, var, unary, binary, conditional, or call
+	Value string      `json:"value,omitempty"`
+	Imag  bool        `json:"imag,omitempty"`
+	Name  string      `json:"name,omitempty"`
+	Op    string      `json:"op,omitempty"`
+	Fn    string      `json:"fn,omitempty"`
+	Cond  *jsonExpr   `json:"cond,omitempty"`
+	X     *jsonExpr   `json:"x,omitempty"`
+	Y     *jsonExpr   `json:"y,omitempty"`
+	Args  []*jsonExpr `json:"args,omitempty"`
+}
+
+func (l literal) MarshalJSON() ([]byte, error)     { return marshalJSON(l) }
+func (v Var) MarshalJSON() ([]byte, error)         { return marshalJSON(v) }
+func (id ident) MarshalJSON() ([]byte, error)      { return marshalJSON(id) }
+func (u unary) MarshalJSON() ([]byte, error)       { return marshalJSON(u) }
+func (b binary) MarshalJSON() ([]byte, error)      { return marshalJSON(b) }
+func (c conditional) MarshalJSON() ([]byte, error) { return marshalJSON(c) }
+func (c call) MarshalJSON() ([]byte, error)        { return marshalJSON(c) }
+
+func marshalJSON(e Expr) ([]byte, error) {
+	j, err := toJSON(e)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+func toJSON(e Expr) (*jsonExpr, error) {
+	switch e := e.(type) {
+	case literal:
+		return &jsonExpr{Kind: "literal", Value: literalText(e), Imag: e.imag}, nil
+	case Var:
+		return &jsonExpr{Kind: "var", Name: string(e)}, nil
+	case ident:
+		return &jsonExpr{Kind: "var", Name: string(e.name)}, nil
+	case unary:
+		x, err := toJSON(e.x)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonExpr{Kind: "unary", Op: opString(e.op), X: x}, nil
+	case binary:
+		x, err := toJSON(e.x)
+		if err != nil {
+			return nil, err
+		}
+		y, err := toJSON(e.y)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonExpr{Kind: "binary", Op: opString(e.op), X: x, Y: y}, nil
+	case conditional:
+		cond, err := toJSON(e.cond)
+		if err != nil {
+			return nil, err
+		}
+		x, err := toJSON(e.x)
+		if err != nil {
+			return nil, err
+		}
+		y, err := toJSON(e.y)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonExpr{Kind: "conditional", Cond: cond, X: x, Y: y}, nil
+	case call:
+		j := &jsonExpr{Kind: "call", Fn: e.fn}
+		for _, arg := range e.args {
+			a, err := toJSON(arg)
+			if err != nil {
+				return nil, err
+			}
+			j.Args = append(j.Args, a)
+		}
+		return j, nil
+	}
+	return nil, fmt.Errorf("cannot encode %T as JSON", e)
+}
+
+// UnmarshalJSON decodes an expression from the JSON encoding
+// produced by the MarshalJSON method of an Expr.
+func UnmarshalJSON(data []byte) (Expr, error) {
+	var j jsonExpr
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	return fromJSON(&j)
+}
+
+func fromJSON(j *jsonExpr) (Expr, error) {
+	if j == nil {
+		return nil, fmt.Errorf("missing operand")
+	}
+	switch j.Kind {
+	case "literal":
+		return parseLiteral(j.Value, j.Imag)
+	case "var":
+		if j.Name == "" {
+			return nil, fmt.Errorf("var has no name")
+		}
+		return Var(j.Name), nil
+	case "unary":
+		op, ok := unaryOp(j.Op)
+		if !ok {
+			return nil, fmt.Errorf("unknown unary op %q", j.Op)
+		}
+		x, err := fromJSON(j.X)
+		if err != nil {
+			return nil, err
+		}
+		return unary{op: op, x: x}, nil
+	case "binary":
+		op, ok := binaryOp(j.Op)
+		if !ok {
+			return nil, fmt.Errorf("unknown binary op %q", j.Op)
+		}
+		x, err := fromJSON(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := fromJSON(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		return binary{op: op, x: x, y: y}, nil
+	case "conditional":
+		cond, err := fromJSON(j.Cond)
+		if err != nil {
+			return nil, err
+		}
+		x, err := fromJSON(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := fromJSON(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		return conditional{cond: cond, x: x, y: y}, nil
+	case "call":
+		c := call{fn: j.Fn}
+		for _, a := range j.Args {
+			arg, err := fromJSON(a)
+			if err != nil {
+				return nil, err
+			}
+			c.args = append(c.args, arg)
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown kind of expression %q", j.Kind)
+}
+
+// literalText returns the text of a literal, without any i suffix.
+func literalText(l literal) string {
+	if l.text != "" {
+		if l.imag {
+			return l.text[:len(l.text)-1]
+		}
+		return l.text
+	}
+	return strconv.FormatFloat(l.val, 'g', -1, 64)
+}
+
+// parseLiteral returns the literal whose text is s.
+func parseLiteral(s string, imag bool) (literal, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return literal{}, err
+	}
+	lit := literal{val: f, imag: imag, text: s}
+	if imag {
+		lit.text += "i"
+	}
+	return lit, nil
+}
+
+// unaryOp returns the unary operator whose text is s.
+func unaryOp(s string) (rune, bool) {
+	switch s {
+	case "+", "-", "!":
+		return rune(s[0]), true
+	}
+	return 0, false
+}
+
+// binaryOp returns the binary operator whose text is s.
+func binaryOp(s string) (rune, bool) {
+	for op, text := range opText {
+		if s == text {
+			return op, true
+		}
+	}
+	if len(s) == 1 && precedence(rune(s[0])) > 0 {
+		return rune(s[0]), true
+	}
+	return 0, false
+}
@@ -0,0 +1,228 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FormatLaTeX formats an expression as LaTeX math, e.g.,
+// \frac{5}{9} \cdot \left(F - 32\right).
+// Unlike Format, it uses only the parentheses that are needed.
+func FormatLaTeX(e Expr) string {
+	var buf bytes.Buffer
+	writeLaTeX(&buf, e)
+	return buf.String()
+}
+
+// greek maps variable names to Greek letters: LaTeX command, Unicode.
+var greek = map[string][2]string{
+	"alpha": {`\alpha`, "α"}, "beta": {`\beta`, "β"}, "gamma": {`\gamma`, "γ"},
+	"delta": {`\delta`, "δ"}, "epsilon": {`\epsilon`, "ε"}, "theta": {`\theta`, "θ"},
+	"lambda": {`\lambda`, "λ"}, "mu": {`\mu`, "μ"}, "pi": {`\pi`, "π"},
+	"rho": {`\rho`, "ρ"}, "sigma": {`\sigma`, "σ"}, "tau": {`\tau`, "τ"},
+	"phi": {`\phi`, "φ"}, "omega": {`\omega`, "ω"},
+}
+
+var latexOps = map[rune]string{
+	'+': "+", '-': "-", '*': `\cdot`, '<': "<", '>': ">",
+	le: `\le`, ge: `\ge`, eq: "=", ne: `\ne`, and: `\land`, or: `\lor`,
+}
+
+// latexFuncs maps functions of one argument to LaTeX operators.
+var latexFuncs = map[string]string{
+	"sin": `\sin`, "cos": `\cos`, "tan": `\tan`, "asin": `\arcsin`,
+	"acos": `\arccos`, "atan": `\arctan`, "log": `\ln`, "log10": `\log_{10}`,
+	"min": `\min`, "max": `\max`, "arg": `\arg`,
+	"real": `\operatorname{Re}`, "imag": `\operatorname{Im}`,
+}
+
+func writeLaTeX(buf *bytes.Buffer, e Expr) {
+	switch e := e.(type) {
+	case literal:
+		s := strconv.FormatFloat(e.val, 'g', -1, 64)
+		switch {
+		case math.IsInf(e.val, 0):
+			s = strings.Replace(s, "Inf", `\infty`, 1)
+		case math.IsNaN(e.val):
+			s = `\mathrm{NaN}`
+		case strings.Contains(s, "e"):
+			// 1e-05 => 1 \times 10^{-5}
+			m, x := splitExponent(s)
+			s = fmt.Sprintf(`%s \times 10^{%s}`, m, x)
+		}
+		buf.WriteString(s)
+		if e.imag {
+			buf.WriteByte('i')
+		}
+
+	case Var:
+		writeLaTeXVar(buf, string(e))
+
+	case ident:
+		writeLaTeXVar(buf, string(e.name))
+
+	case unary:
+		if e.op == '!' {
+			buf.WriteString(`\lnot `)
+		} else {
+			buf.WriteRune(e.op)
+		}
+		writeLaTeXOperand(buf, e.x, latexPrec(e.x) < prec(e) || isUnary(e.x))
+
+	case binary:
+		if e.op == '/' {
+			buf.WriteString(`\frac{`)
+			writeLaTeX(buf, e.x)
+			buf.WriteString("}{")
+			writeLaTeX(buf, e.y)
+			buf.WriteString("}")
+			return
+		}
+		left, right := needParens(e)
+		writeLaTeXOperand(buf, e.x, left)
+		fmt.Fprintf(buf, " %s ", latexOps[e.op])
+		writeLaTeXOperand(buf, e.y, right)
+
+	case conditional:
+		buf.WriteString(`\begin{cases} `)
+		writeLaTeX(buf, e.x)
+		buf.WriteString(` & \text{if } `)
+		writeLaTeX(buf, e.cond)
+		buf.WriteString(` \\ `)
+		writeLaTeX(buf, e.y)
+		buf.WriteString(` & \text{otherwise} \end{cases}`)
+
+	case call:
+		writeLaTeXCall(buf, e)
+
+	default:
+		panic(fmt.Sprintf("unknown Expr: %T", e))
+	}
+}
+
+func writeLaTeXVar(buf *bytes.Buffer, name string) {
+	if g, ok := greek[name]; ok {
+		buf.WriteString(g[0])
+	} else if len(name) == 1 {
+		buf.WriteString(name)
+	} else {
+		fmt.Fprintf(buf, `\mathit{%s}`, strings.Replace(name, "_", `\_`, -1))
+	}
+}
+
+func writeLaTeXOperand(buf *bytes.Buffer, e Expr, paren bool) {
+	if paren {
+		buf.WriteString(`\left(`)
+	}
+	writeLaTeX(buf, e)
+	if paren {
+		buf.WriteString(`\right)`)
+	}
+}
+
+func writeLaTeXCall(buf *bytes.Buffer, c call) {
+	if len(c.args) == 1 {
+		x := c.args[0]
+		switch c.fn {
+		case "sqrt":
+			buf.WriteString(`\sqrt{`)
+			writeLaTeX(buf, x)
+			buf.WriteString("}")
+			return
+		case "exp":
+			buf.WriteString("e^{")
+			writeLaTeX(buf, x)
+			buf.WriteString("}")
+			return
+		case "abs", "floor", "ceil":
+			delims := map[string][2]string{
+				"abs":   {`\left|`, `\right|`},
+				"floor": {`\left\lfloor `, ` \right\rfloor`},
+				"ceil":  {`\left\lceil `, ` \right\rceil`},
+			}[c.fn]
+			buf.WriteString(delims[0])
+			writeLaTeX(buf, x)
+			buf.WriteString(delims[1])
+			return
+		case "conj":
+			buf.WriteString(`\overline{`)
+			writeLaTeX(buf, x)
+			buf.WriteString("}")
+			return
+		}
+	}
+	if c.fn == "pow" && len(c.args) == 2 {
+		writeLaTeXOperand(buf, c.args[0], latexPrec(c.args[0]) < atom || isUnary(c.args[0]))
+		buf.WriteString("^{")
+		writeLaTeX(buf, c.args[1])
+		buf.WriteString("}")
+		return
+	}
+	if op, ok := latexFuncs[c.fn]; ok {
+		buf.WriteString(op)
+	} else {
+		fmt.Fprintf(buf, `\operatorname{%s}`, c.fn)
+	}
+	buf.WriteString(`\left(`)
+	for i, arg := range c.args {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		writeLaTeX(buf, arg)
+	}
+	buf.WriteString(`\right)`)
+}
+
+// atom is the precedence of an expression that never needs parentheses.
+var atom = prec(Var(""))
+
+// latexPrec is like prec, but treats a fraction as an atom, and a
+// power (which has a superscript) as needing parentheses only where
+// a unary expression does.
+func latexPrec(e Expr) int {
+	switch e := e.(type) {
+	case binary:
+		if e.op == '/' {
+			return atom
+		}
+	case call:
+		if e.fn == "pow" && len(e.args) == 2 {
+			return atom - 1
+		}
+	}
+	return prec(e)
+}
+
+// needParens reports whether the left and right operands of a binary
+// expression other than division need parentheses in mathematical
+// notation.  Because + and * are associative, a + (b - c) is written
+// a + b - c, but a - (b + c) keeps its parentheses.
+func needParens(e binary) (left, right bool) {
+	p := precedence(e.op)
+	left = latexPrec(e.x) < p
+	right = latexPrec(e.y) < p || isUnary(e.y)
+	if latexPrec(e.y) == p && !associative(e.op) {
+		right = true
+	}
+	return left, right
+}
+
+// splitExponent splits a number formatted by %g such as 1e-05
+// into its mantissa and exponent, 1 and -5.
+func splitExponent(s string) (mantissa, exp string) {
+	i := strings.IndexByte(s, 'e')
+	x, _ := strconv.Atoi(s[i+1:])
+	return s[:i], strconv.Itoa(x)
+}
+
+// associative reports whether the operators of precedence equal to
+// op's may be regrouped without changing the mathematical value.
+func associative(op rune) bool {
+	return op == '+' || op == '*' || op == and || op == or
+}
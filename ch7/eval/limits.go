@@ -0,0 +1,102 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"context"
+	"fmt"
+)
+
+// Limits bounds the resources that ParseLimited may use, which
+// protects a program that parses untrusted input.  A zero field
+// means no limit.
+type Limits struct {
+	MaxLength int // maximum length of the input, in bytes
+	MaxDepth  int // maximum nesting depth of parentheses and operators
+	MaxNodes  int // maximum number of nodes in the syntax tree
+}
+
+// A LimitError reports that an input or evaluation exceeded a limit.
+type LimitError struct {
+	Limit string // "length", "depth", "nodes", or "steps"
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case "length":
+		return fmt.Sprintf("expression too long (maximum %d bytes)", e.Max)
+	case "depth":
+		return fmt.Sprintf("expression nested too deeply (maximum depth %d)", e.Max)
+	case "nodes":
+		return fmt.Sprintf("expression too large (maximum %d nodes)", e.Max)
+	}
+	return fmt.Sprintf("evaluation too costly (maximum %d %s)", e.Max, e.Limit)
+}
+
+// A Meter limits the total work done by a series of evaluations,
+// and stops them when a context is cancelled.  Since expressions
+// have no loops, the cost of evaluating an expression is taken to
+// be its number of nodes.
+type Meter struct {
+	ctx   context.Context
+	max   int // maximum number of steps; 0 means no limit
+	steps int
+	calls int
+}
+
+// NewMeter returns a Meter that permits at most max steps of
+// evaluation, if max is positive, while ctx is not done.
+func NewMeter(ctx context.Context, max int) *Meter {
+	return &Meter{ctx: ctx, max: max}
+}
+
+// Charge records n steps of evaluation.  It returns a *LimitError
+// if the budget is exhausted, or the context's error if it is done.
+func (m *Meter) Charge(n int) error {
+	m.steps += n
+	if m.max > 0 && m.steps > m.max {
+		return &LimitError{"steps", m.max}
+	}
+	// Checking the context is relatively costly, so do it only occasionally.
+	if m.calls++; m.calls%64 == 0 {
+		return m.ctx.Err()
+	}
+	return nil
+}
+
+// Eval charges for and evaluates e in the environment env.
+func (m *Meter) Eval(e Expr, env Env) (float64, error) {
+	if err := m.Charge(size(e)); err != nil {
+		return 0, err
+	}
+	return e.Eval(env), nil
+}
+
+// EvalProgram charges for and evaluates the program p.
+func (m *Meter) EvalProgram(p *Program, slots []float64) (float64, error) {
+	if err := m.Charge(len(p.code)); err != nil {
+		return 0, err
+	}
+	return p.Eval(slots), nil
+}
+
+// size returns the number of nodes in e.
+func size(e Expr) int {
+	switch e := e.(type) {
+	case unary:
+		return 1 + size(e.x)
+	case binary:
+		return 1 + size(e.x) + size(e.y)
+	case conditional:
+		return 1 + size(e.cond) + size(e.x) + size(e.y)
+	case call:
+		n := 1
+		for _, arg := range e.args {
+			n += size(arg)
+		}
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,60 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseLimited(t *testing.T) {
+	limits := Limits{MaxLength: 1000, MaxDepth: 20, MaxNodes: 50}
+	for _, test := range []struct{ input, want string }{
+		{"sqrt(A / pi)", ""},
+		{strings.Repeat("x+", 500) + "x", "expression too long (maximum 1000 bytes)"},
+		{strings.Repeat("(", 30) + "x" + strings.Repeat(")", 30), "expression nested too deeply (maximum depth 20)"},
+		{strings.Repeat("-", 30) + "x", "expression nested too deeply (maximum depth 20)"},
+		{strings.Repeat("x+", 30) + "x", "expression too large (maximum 50 nodes)"},
+	} {
+		_, err := ParseLimited(test.input, limits)
+		if test.want == "" {
+			if err != nil {
+				t.Errorf("ParseLimited(%.20s...): %v", test.input, err)
+			}
+			continue
+		}
+		if _, ok := err.(*LimitError); !ok || err.Error() != test.want {
+			t.Errorf("ParseLimited(%.20s...) returned error %v, want %s", test.input, err, test.want)
+		}
+	}
+}
+
+func TestMeter(t *testing.T) {
+	expr, err := Parse("x * x + 1") // 5 nodes
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMeter(context.Background(), 12)
+	for i := 0; i < 2; i++ {
+		if _, err := m.Eval(expr, Env{"x": 2}); err != nil {
+			t.Fatalf("evaluation %d: %v", i, err)
+		}
+	}
+	if _, err := m.Eval(expr, Env{"x": 2}); err == nil || err.Error() != "evaluation too costly (maximum 12 steps)" {
+		t.Errorf("third evaluation returned error %v, want budget exhausted", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m = NewMeter(ctx, 0)
+	for i := 0; i < 100; i++ {
+		if _, err = m.Eval(expr, nil); err != nil {
+			break
+		}
+	}
+	if err != context.Canceled {
+		t.Errorf("evaluation with cancelled context returned %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FormatMathML formats an expression as a MathML <math> element,
+// using the same notation and parentheses as FormatLaTeX.
+func FormatMathML(e Expr) string {
+	var buf bytes.Buffer
+	buf.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML">`)
+	writeMathML(&buf, e)
+	buf.WriteString("</math>")
+	return buf.String()
+}
+
+var mathmlOps = map[rune]string{
+	'+': "+", '-': "-", '*': "⋅", '<': "&lt;", '>': "&gt;",
+	le: "≤", ge: "≥", eq: "=", ne: "≠", and: "∧", or: "∨", '!': "¬",
+}
+
+// mathmlFuncs maps functions to MathML identifiers where they differ.
+var mathmlFuncs = map[string]string{
+	"asin": "arcsin", "acos": "arccos", "atan": "arctan", "log": "ln",
+	"real": "Re", "imag": "Im",
+}
+
+func writeMathML(buf *bytes.Buffer, e Expr) {
+	switch e := e.(type) {
+	case literal:
+		s := strconv.FormatFloat(e.val, 'g', -1, 64)
+		neg := strings.HasPrefix(s, "-")
+		if neg {
+			buf.WriteString("<mrow><mo>-</mo>")
+			s = s[1:]
+		}
+		switch {
+		case math.IsInf(e.val, 0):
+			buf.WriteString("<mi>∞</mi>")
+		case math.IsNaN(e.val):
+			buf.WriteString("<mi>NaN</mi>")
+		case strings.Contains(s, "e"):
+			m, x := splitExponent(s)
+			fmt.Fprintf(buf, "<mrow><mn>%s</mn><mo>×</mo><msup><mn>10</mn><mn>%s</mn></msup></mrow>", m, x)
+		default:
+			fmt.Fprintf(buf, "<mn>%s</mn>", s)
+		}
+		if e.imag {
+			buf.WriteString("<mo>&#x2062;</mo><mi>i</mi>") // invisible times
+		}
+		if neg {
+			buf.WriteString("</mrow>")
+		}
+
+	case Var:
+		writeMathMLVar(buf, string(e))
+
+	case ident:
+		writeMathMLVar(buf, string(e.name))
+
+	case unary:
+		fmt.Fprintf(buf, "<mrow><mo>%s</mo>", mathmlOps[e.op])
+		writeMathMLOperand(buf, e.x, latexPrec(e.x) < prec(e) || isUnary(e.x))
+		buf.WriteString("</mrow>")
+
+	case binary:
+		if e.op == '/' {
+			buf.WriteString("<mfrac>")
+			writeMathML(buf, e.x)
+			writeMathML(buf, e.y)
+			buf.WriteString("</mfrac>")
+			return
+		}
+		left, right := needParens(e)
+		buf.WriteString("<mrow>")
+		writeMathMLOperand(buf, e.x, left)
+		fmt.Fprintf(buf, "<mo>%s</mo>", mathmlOps[e.op])
+		writeMathMLOperand(buf, e.y, right)
+		buf.WriteString("</mrow>")
+
+	case conditional:
+		buf.WriteString("<mrow><mo>{</mo><mtable><mtr><mtd>")
+		writeMathML(buf, e.x)
+		buf.WriteString("</mtd><mtd><mtext>if&#xA0;</mtext>")
+		writeMathML(buf, e.cond)
+		buf.WriteString("</mtd></mtr><mtr><mtd>")
+		writeMathML(buf, e.y)
+		buf.WriteString("</mtd><mtd><mtext>otherwise</mtext></mtd></mtr></mtable></mrow>")
+
+	case call:
+		writeMathMLCall(buf, e)
+
+	default:
+		panic(fmt.Sprintf("unknown Expr: %T", e))
+	}
+}
+
+func writeMathMLVar(buf *bytes.Buffer, name string) {
+	if g, ok := greek[name]; ok {
+		name = g[1]
+	}
+	fmt.Fprintf(buf, "<mi>%s</mi>", html.EscapeString(name))
+}
+
+func writeMathMLOperand(buf *bytes.Buffer, e Expr, paren bool) {
+	if paren {
+		buf.WriteString("<mrow><mo>(</mo>")
+	}
+	writeMathML(buf, e)
+	if paren {
+		buf.WriteString("<mo>)</mo></mrow>")
+	}
+}
+
+func writeMathMLCall(buf *bytes.Buffer, c call) {
+	if len(c.args) == 1 {
+		x := c.args[0]
+		switch c.fn {
+		case "sqrt":
+			buf.WriteString("<msqrt>")
+			writeMathML(buf, x)
+			buf.WriteString("</msqrt>")
+			return
+		case "exp":
+			buf.WriteString("<msup><mi>e</mi>")
+			writeMathML(buf, x)
+			buf.WriteString("</msup>")
+			return
+		case "abs", "floor", "ceil":
+			delims := map[string][2]string{
+				"abs":   {"|", "|"},
+				"floor": {"⌊", "⌋"},
+				"ceil":  {"⌈", "⌉"},
+			}[c.fn]
+			fmt.Fprintf(buf, "<mrow><mo>%s</mo>", delims[0])
+			writeMathML(buf, x)
+			fmt.Fprintf(buf, "<mo>%s</mo></mrow>", delims[1])
+			return
+		case "conj":
+			buf.WriteString("<mover>")
+			writeMathML(buf, x)
+			buf.WriteString("<mo>¯</mo></mover>")
+			return
+		}
+	}
+	if c.fn == "pow" && len(c.args) == 2 {
+		buf.WriteString("<msup>")
+		writeMathMLOperand(buf, c.args[0], latexPrec(c.args[0]) < atom || isUnary(c.args[0]))
+		writeMathML(buf, c.args[1])
+		buf.WriteString("</msup>")
+		return
+	}
+	buf.WriteString("<mrow>")
+	if c.fn == "log10" {
+		buf.WriteString("<msub><mi>log</mi><mn>10</mn></msub>")
+	} else if fn, ok := mathmlFuncs[c.fn]; ok {
+		fmt.Fprintf(buf, "<mi>%s</mi>", fn)
+	} else {
+		fmt.Fprintf(buf, "<mi>%s</mi>", html.EscapeString(c.fn))
+	}
+	buf.WriteString("<mo>&#x2061;</mo><mrow><mo>(</mo>") // function application
+	for i, arg := range c.args {
+		if i > 0 {
+			buf.WriteString("<mo>,</mo>")
+		}
+		writeMathML(buf, arg)
+	}
+	buf.WriteString("<mo>)</mo></mrow></mrow>")
+}
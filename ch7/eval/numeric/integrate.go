@@ -0,0 +1,80 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package numeric
+
+import (
+	"math"
+
+	"gopl.io/ch7/eval"
+)
+
+// maxDepth limits the recursive subdivision of Integrate.
+const maxDepth = 50
+
+// Integrate returns the integral of e, as a function of v,
+// over [a, b] using adaptive Simpson's rule.
+func Integrate(e eval.Expr, v eval.Var, env eval.Env, a, b float64) (float64, error) {
+	f, err := newFunction(e, v, env)
+	if err != nil {
+		return 0, err
+	}
+	fa, err := f.at(a)
+	if err != nil {
+		return 0, err
+	}
+	fb, err := f.at(b)
+	if err != nil {
+		return 0, err
+	}
+	m := a + (b-a)/2
+	fm, err := f.at(m)
+	if err != nil {
+		return 0, err
+	}
+	whole := simpson(a, b, fa, fm, fb)
+	eps := tolerance * math.Max(1, math.Abs(whole))
+	return f.simpson(a, b, fa, fm, fb, whole, eps, maxDepth)
+}
+
+// simpson returns Simpson's estimate of the integral over [a, b]
+// given the values at a, the midpoint and b.
+func simpson(a, b, fa, fm, fb float64) float64 {
+	return (b - a) / 6 * (fa + 4*fm + fb)
+}
+
+// simpson recursively refines the estimate whole of the integral
+// over [a, b] until it is within eps.
+func (f *function) simpson(a, b, fa, fm, fb, whole, eps float64, depth int) (float64, error) {
+	m := a + (b-a)/2
+	lm, rm := a+(m-a)/2, m+(b-m)/2
+	flm, err := f.at(lm)
+	if err != nil {
+		return 0, err
+	}
+	frm, err := f.at(rm)
+	if err != nil {
+		return 0, err
+	}
+	left := simpson(a, m, fa, flm, fm)
+	right := simpson(m, b, fm, frm, fb)
+	delta := left + right - whole
+	if math.Abs(delta) <= 15*eps || near(a, b) {
+		// Accept the estimate if it is good enough, or if the
+		// interval can be divided no further, as happens near
+		// a discontinuity.
+		return left + right + delta/15, nil
+	}
+	if depth == 0 {
+		return 0, ErrNoConvergence
+	}
+	l, err := f.simpson(a, m, fa, flm, fm, left, eps/2, depth-1)
+	if err != nil {
+		return 0, err
+	}
+	r, err := f.simpson(m, b, fm, frm, fb, right, eps/2, depth-1)
+	if err != nil {
+		return 0, err
+	}
+	return l + r, nil
+}
@@ -0,0 +1,52 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package numeric
+
+import (
+	"math"
+
+	"gopl.io/ch7/eval"
+)
+
+// invPhi is the reciprocal of the golden ratio.
+var invPhi = (math.Sqrt(5) - 1) / 2
+
+// Minimize returns the location of a local minimum of e, as a
+// function of v, within [a, b] using golden-section search.
+// If e is not unimodal on [a, b], the result may be any local
+// minimum, or one of the end points.
+func Minimize(e eval.Expr, v eval.Var, env eval.Env, a, b float64) (float64, error) {
+	f, err := newFunction(e, v, env)
+	if err != nil {
+		return 0, err
+	}
+	c, d := b-invPhi*(b-a), a+invPhi*(b-a)
+	fc, err := f.at(c)
+	if err != nil {
+		return 0, err
+	}
+	fd, err := f.at(d)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < maxIter; i++ {
+		if near(a, b) {
+			return a + (b-a)/2, nil
+		}
+		if fc < fd {
+			b, d, fd = d, c, fc
+			c = b - invPhi*(b-a)
+			if fc, err = f.at(c); err != nil {
+				return 0, err
+			}
+		} else {
+			a, c, fc = c, d, fd
+			d = a + invPhi*(b-a)
+			if fd, err = f.at(d); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return 0, ErrNoConvergence
+}
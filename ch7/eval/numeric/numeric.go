@@ -0,0 +1,77 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+// Package numeric provides numerical solvers for expressions
+// parsed by package eval: root finding, integration and minimization.
+//
+// Each solver treats an expression as a function of one variable;
+// all other variables take their values from a fixed environment.
+// Unlike eval.Expr.Eval, the solvers report an error if the function
+// yields a NaN or infinite value.
+package numeric
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gopl.io/ch7/eval"
+)
+
+const (
+	tolerance = 1e-10 // relative tolerance of results
+	maxIter   = 200   // maximum number of iterations or subdivisions
+)
+
+// ErrNoConvergence is returned when a solver fails to reach
+// the required tolerance.
+var ErrNoConvergence = errors.New("numeric: no convergence")
+
+// A NonFiniteError records a NaN or infinite function value.
+type NonFiniteError struct {
+	Var  eval.Var
+	X, Y float64 // Y is the non-finite value of the function at X
+}
+
+func (e *NonFiniteError) Error() string {
+	return fmt.Sprintf("numeric: value at %s=%g is %g", e.Var, e.X, e.Y)
+}
+
+// A function is an expression considered as a function of v.
+type function struct {
+	e   eval.Expr
+	v   eval.Var
+	env eval.Env
+}
+
+func newFunction(e eval.Expr, v eval.Var, env eval.Env) (*function, error) {
+	vars := make(map[eval.Var]bool)
+	if err := e.Check(vars); err != nil {
+		return nil, err
+	}
+	f := &function{e, v, eval.Env{}}
+	for w := range vars {
+		if _, ok := env[w]; !ok && w != v {
+			return nil, fmt.Errorf("undefined variable: %s", w)
+		}
+	}
+	for w, x := range env {
+		f.env[w] = x
+	}
+	return f, nil
+}
+
+// at returns the value of f at x.
+func (f *function) at(x float64) (float64, error) {
+	f.env[f.v] = x
+	y := f.e.Eval(f.env)
+	if math.IsNaN(y) || math.IsInf(y, 0) {
+		return 0, &NonFiniteError{f.v, x, y}
+	}
+	return y, nil
+}
+
+// near reports whether x and y agree to within the tolerance.
+func near(x, y float64) bool {
+	return math.Abs(x-y) <= tolerance*math.Max(1, math.Abs(x))
+}
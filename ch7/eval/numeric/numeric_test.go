@@ -0,0 +1,121 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package numeric
+
+import (
+	"math"
+	"testing"
+
+	"gopl.io/ch7/eval"
+)
+
+func parse(t *testing.T, s string) eval.Expr {
+	e, err := eval.Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%s): %v", s, err)
+	}
+	return e
+}
+
+func TestRoots(t *testing.T) {
+	for _, test := range []struct {
+		expr string
+		env  eval.Env
+		a, b float64
+		want float64
+	}{
+		{"x * x - 2", nil, 0, 2, math.Sqrt2},
+		{"cos(x) - x", nil, 0, 1, 0.7390851332151607},
+		{"pow(x, 3) - k", eval.Env{"k": 27}, 1, 4, 3},
+		{"x - 1", nil, 1, 5, 1},
+	} {
+		e := parse(t, test.expr)
+		got, err := Bisect(e, "x", test.env, test.a, test.b)
+		if err != nil || math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("Bisect(%s) = %g, %v, want %g", test.expr, got, err, test.want)
+		}
+		got, err = Newton(e, "x", test.env, test.b)
+		if err != nil || math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("Newton(%s) = %g, %v, want %g", test.expr, got, err, test.want)
+		}
+	}
+}
+
+func TestIntegrate(t *testing.T) {
+	for _, test := range []struct {
+		expr string
+		a, b float64
+		want float64
+	}{
+		{"x * x", 0, 3, 9},
+		{"sin(x)", 0, math.Pi, 2},
+		{"sqrt(1 - x * x)", -1, 1, math.Pi / 2},
+		{"exp(-x * x)", -5, 5, math.Sqrt(math.Pi)},
+		{"x > 1 ? 1 : 0", 0, 2, 1},
+	} {
+		got, err := Integrate(parse(t, test.expr), "x", nil, test.a, test.b)
+		if err != nil || math.Abs(got-test.want) > 1e-7 {
+			t.Errorf("Integrate(%s) = %g, %v, want %g", test.expr, got, err, test.want)
+		}
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	for _, test := range []struct {
+		expr string
+		a, b float64
+		want float64
+	}{
+		{"pow(x - 2, 2) + 1", -10, 10, 2},
+		{"cos(x)", 0, 5, math.Pi},
+		{"abs(x + 0.5)", -3, 3, -0.5},
+	} {
+		got, err := Minimize(parse(t, test.expr), "x", nil, test.a, test.b)
+		if err != nil || math.Abs(got-test.want) > 1e-6 {
+			t.Errorf("Minimize(%s) = %g, %v, want %g", test.expr, got, err, test.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		err  func() error
+		want string
+	}{
+		{"bisect same sign", func() error {
+			_, err := Bisect(parse(t, "x * x + 1"), "x", nil, -1, 1)
+			return err
+		}, "numeric: no sign change in [-1, 1]"},
+		{"bisect undefined", func() error {
+			_, err := Bisect(parse(t, "x - k"), "x", nil, -1, 1)
+			return err
+		}, "undefined variable: k"},
+		{"newton zero derivative", func() error {
+			_, err := Newton(parse(t, "x * x + 1"), "x", nil, 0)
+			return err
+		}, "numeric: zero derivative at x=0"},
+		{"newton no convergence", func() error {
+			_, err := Newton(parse(t, "x * x + 1"), "x", nil, 0.5)
+			return err
+		}, ErrNoConvergence.Error()},
+		{"integrate NaN", func() error {
+			_, err := Integrate(parse(t, "sqrt(x)"), "x", nil, -1, 1)
+			return err
+		}, "numeric: value at x=-1 is NaN"},
+		{"integrate Inf", func() error {
+			_, err := Integrate(parse(t, "1 / x"), "x", nil, -1, 1)
+			return err
+		}, "numeric: value at x=0 is +Inf"},
+		{"minimize NaN", func() error {
+			_, err := Minimize(parse(t, "log(x)"), "x", nil, -2, -1)
+			return err
+		}, "numeric: value at x=-1.618033988749895 is NaN"},
+	} {
+		err := test.err()
+		if err == nil || err.Error() != test.want {
+			t.Errorf("%s: got error %v, want %s", test.name, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package numeric
+
+import (
+	"fmt"
+
+	"gopl.io/ch7/eval"
+)
+
+// Bisect returns a root of e, as a function of v, in the interval
+// [a, b] using the bisection method.  The values of e at a and b
+// must have opposite signs.
+func Bisect(e eval.Expr, v eval.Var, env eval.Env, a, b float64) (float64, error) {
+	f, err := newFunction(e, v, env)
+	if err != nil {
+		return 0, err
+	}
+	fa, err := f.at(a)
+	if err != nil {
+		return 0, err
+	}
+	fb, err := f.at(b)
+	if err != nil {
+		return 0, err
+	}
+	if (fa < 0) == (fb < 0) && fa != 0 && fb != 0 {
+		return 0, fmt.Errorf("numeric: no sign change in [%g, %g]", a, b)
+	}
+	for i := 0; i < maxIter; i++ {
+		if fa == 0 {
+			return a, nil
+		}
+		if fb == 0 || near(a, b) {
+			return b, nil
+		}
+		m := a + (b-a)/2
+		fm, err := f.at(m)
+		if err != nil {
+			return 0, err
+		}
+		if (fm < 0) == (fa < 0) {
+			a, fa = m, fm
+		} else {
+			b, fb = m, fm
+		}
+	}
+	return 0, ErrNoConvergence
+}
+
+// Newton returns a root of e, as a function of v, using Newton's
+// method starting from x.  The derivative is computed symbolically
+// by eval.Derive.
+func Newton(e eval.Expr, v eval.Var, env eval.Env, x float64) (float64, error) {
+	f, err := newFunction(e, v, env)
+	if err != nil {
+		return 0, err
+	}
+	d, err := eval.Derive(e, v)
+	if err != nil {
+		return 0, err
+	}
+	df := &function{d, v, f.env} // shares f's environment
+	for i := 0; i < maxIter; i++ {
+		y, err := f.at(x)
+		if err != nil {
+			return 0, err
+		}
+		dy, err := df.at(x)
+		if err != nil {
+			return 0, err
+		}
+		if dy == 0 {
+			if y == 0 {
+				return x, nil
+			}
+			return 0, fmt.Errorf("numeric: zero derivative at %s=%g", v, x)
+		}
+		next := x - y/dy
+		if near(next, x) {
+			return next, nil
+		}
+		x = next
+	}
+	return 0, ErrNoConvergence
+}
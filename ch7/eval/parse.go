@@ -15,14 +15,64 @@ import (
 // This lexer is similar to the one described in Chapter 13.
 type lexer struct {
 	scan  scanner.Scanner
-	token rune // current lookahead token
+	token rune             // current lookahead token
+	pos   scanner.Position // position of token
+	imag  string           // text of an imaginary token, without the i
+
+	limits       Limits
+	depth, nodes int // current nesting depth and number of nodes
+}
+
+func (lex *lexer) text() string {
+	if lex.token == imaginary {
+		return lex.imag
+	}
+	return lex.scan.TokenText()
 }
 
-func (lex *lexer) next()        { lex.token = lex.scan.Scan() }
-func (lex *lexer) text() string { return lex.scan.TokenText() }
+func (lex *lexer) next() {
+	lex.token = lex.scan.Scan()
+	lex.pos = lex.scan.Position
+	// Combine the two runes of an operator such as <= into one token.
+	for op, text := range opText {
+		if lex.token == rune(text[0]) && lex.scan.Peek() == rune(text[1]) {
+			lex.scan.Next() // consume second rune
+			lex.token = op
+			break
+		}
+	}
+	// A number immediately followed by i is imaginary.
+	if (lex.token == scanner.Int || lex.token == scanner.Float) && lex.scan.Peek() == 'i' {
+		lex.imag = lex.scan.TokenText()
+		lex.scan.Next() // consume 'i'
+		lex.token = imaginary
+	}
+}
 
 type lexPanic string
 
+// enter and leave track the nesting depth of the parser.
+func (lex *lexer) enter() {
+	lex.depth++
+	if max := lex.limits.MaxDepth; max > 0 && lex.depth > max {
+		panic(&LimitError{"depth", max})
+	}
+}
+
+func (lex *lexer) leave() { lex.depth-- }
+
+// node counts a new node of the syntax tree.
+func (lex *lexer) node() {
+	lex.nodes++
+	if max := lex.limits.MaxNodes; max > 0 && lex.nodes > max {
+		panic(&LimitError{"nodes", max})
+	}
+}
+
+// imaginary is the token for an imaginary literal, e.g., 2i.
+// Its value is distinct from all tokens of text/scanner.
+const imaginary = -99
+
 // describe returns a string describing the current token, for use in errors.
 func (lex *lexer) describe() string {
 	switch lex.token {
@@ -32,6 +82,11 @@ func (lex *lexer) describe() string {
 		return fmt.Sprintf("identifier %s", lex.text())
 	case scanner.Int, scanner.Float:
 		return fmt.Sprintf("number %s", lex.text())
+	case imaginary:
+		return fmt.Sprintf("number %si", lex.text())
+	}
+	if s, ok := opText[lex.token]; ok {
+		return fmt.Sprintf("%q", s)
 	}
 	return fmt.Sprintf("%q", rune(lex.token)) // any other rune
 }
@@ -39,8 +94,14 @@ func (lex *lexer) describe() string {
 func precedence(op rune) int {
 	switch op {
 	case '*', '/':
-		return 2
+		return 5
 	case '+', '-':
+		return 4
+	case '<', '>', le, ge, eq, ne:
+		return 3
+	case and:
+		return 2
+	case or:
 		return 1
 	}
 	return 0
@@ -53,33 +114,69 @@ func precedence(op rune) int {
 //   expr = num                         a literal number, e.g., 3.14159
 //        | id                          a variable name, e.g., x
 //        | id '(' expr ',' ... ')'     a function call
-//        | '-' expr                    a unary operator (+-)
-//        | expr '+' expr               a binary operator (+-*/)
+//        | '-' expr                    a unary operator (+-!)
+//        | expr '+' expr               a binary operator (+-*/ < <= etc, && ||)
+//        | expr '?' expr ':' expr      a conditional
+//
+// Comparison and logical operators yield 1 for true and 0 for false.
 //
-func Parse(input string) (_ Expr, err error) {
+// A syntax error is reported as an *Error giving its position.
+func Parse(input string) (Expr, error) { return ParseLimited(input, Limits{}) }
+
+// ParseLimited is like Parse but reports a *LimitError if the
+// input exceeds any of the limits.
+func ParseLimited(input string, limits Limits) (_ Expr, err error) {
+	if limits.MaxLength > 0 && len(input) > limits.MaxLength {
+		return nil, &LimitError{"length", limits.MaxLength}
+	}
+	lex := &lexer{limits: limits}
 	defer func() {
 		switch x := recover().(type) {
 		case nil:
 			// no panic
 		case lexPanic:
-			err = fmt.Errorf("%s", x)
+			err = &Error{lex.pos, string(x)}
+		case *LimitError:
+			err = x
 		default:
 			// unexpected panic: resume state of panic.
 			panic(x)
 		}
 	}()
-	lex := new(lexer)
 	lex.scan.Init(strings.NewReader(input))
 	lex.scan.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats
+	lex.scan.Error = func(s *scanner.Scanner, msg string) {
+		lex.pos = s.Pos()
+		panic(lexPanic(msg))
+	}
 	lex.next() // initial lookahead
 	e := parseExpr(lex)
 	if lex.token != scanner.EOF {
-		return nil, fmt.Errorf("unexpected %s", lex.describe())
+		return nil, &Error{lex.pos, fmt.Sprintf("unexpected %s", lex.describe())}
 	}
 	return e, nil
 }
 
-func parseExpr(lex *lexer) Expr { return parseBinary(lex, 1) }
+// expr = binary ('?' expr ':' expr)?
+func parseExpr(lex *lexer) Expr {
+	lex.enter()
+	defer lex.leave()
+	cond := parseBinary(lex, 1)
+	if lex.token != '?' {
+		return cond
+	}
+	pos := lex.pos
+	lex.next() // consume '?'
+	x := parseExpr(lex)
+	if lex.token != ':' {
+		msg := fmt.Sprintf("got %s, want ':'", lex.describe())
+		panic(lexPanic(msg))
+	}
+	lex.next() // consume ':'
+	y := parseExpr(lex)
+	lex.node()
+	return conditional{cond, x, y, pos}
+}
 
 // binary = unary ('+' binary)*
 // parseBinary stops when it encounters an
@@ -88,10 +185,11 @@ func parseBinary(lex *lexer, prec1 int) Expr {
 	lhs := parseUnary(lex)
 	for prec := precedence(lex.token); prec >= prec1; prec-- {
 		for precedence(lex.token) == prec {
-			op := lex.token
+			op, pos := lex.token, lex.pos
 			lex.next() // consume operator
 			rhs := parseBinary(lex, prec+1)
-			lhs = binary{op, lhs, rhs}
+			lex.node()
+			lhs = binary{op, lhs, rhs, pos}
 		}
 	}
 	return lhs
@@ -99,10 +197,13 @@ func parseBinary(lex *lexer, prec1 int) Expr {
 
 // unary = '+' expr | primary
 func parseUnary(lex *lexer) Expr {
-	if lex.token == '+' || lex.token == '-' {
-		op := lex.token
-		lex.next() // consume '+' or '-'
-		return unary{op, parseUnary(lex)}
+	lex.enter()
+	defer lex.leave()
+	if lex.token == '+' || lex.token == '-' || lex.token == '!' {
+		op, pos := lex.token, lex.pos
+		lex.next() // consume '+', '-' or '!'
+		lex.node()
+		return unary{op, parseUnary(lex), pos}
 	}
 	return parsePrimary(lex)
 }
@@ -114,10 +215,11 @@ func parseUnary(lex *lexer) Expr {
 func parsePrimary(lex *lexer) Expr {
 	switch lex.token {
 	case scanner.Ident:
-		id := lex.text()
+		id, pos := lex.text(), lex.pos
 		lex.next() // consume Ident
+		lex.node()
 		if lex.token != '(' {
-			return Var(id)
+			return ident{Var(id), pos}
 		}
 		lex.next() // consume '('
 		var args []Expr
@@ -135,15 +237,22 @@ func parsePrimary(lex *lexer) Expr {
 			}
 		}
 		lex.next() // consume ')'
-		return call{id, args}
+		return call{id, args, pos}
 
-	case scanner.Int, scanner.Float:
-		f, err := strconv.ParseFloat(lex.text(), 64)
+	case scanner.Int, scanner.Float, imaginary:
+		text := lex.text() // excludes the i of an imaginary number
+		f, err := strconv.ParseFloat(text, 64)
 		if err != nil {
 			panic(lexPanic(err.Error()))
 		}
+		lex.node()
+		lit := literal{val: f, text: text, pos: lex.pos}
+		if lex.token == imaginary {
+			lit.imag = true
+			lit.text += "i"
+		}
 		lex.next() // consume number
-		return literal(f)
+		return lit
 
 	case '(':
 		lex.next() // consume ')'
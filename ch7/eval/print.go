@@ -25,11 +25,17 @@ func Format(e Expr) string {
 func write(buf *bytes.Buffer, e Expr) {
 	switch e := e.(type) {
 	case literal:
-		fmt.Fprintf(buf, "%g", e)
+		fmt.Fprintf(buf, "%g", e.val)
+		if e.imag {
+			buf.WriteByte('i')
+		}
 
 	case Var:
 		fmt.Fprintf(buf, "%s", e)
 
+	case ident:
+		fmt.Fprintf(buf, "%s", e.name)
+
 	case unary:
 		fmt.Fprintf(buf, "(%c", e.op)
 		write(buf, e.x)
@@ -45,7 +51,16 @@ func write(buf *bytes.Buffer, e Expr) {
 	case binary:
 		buf.WriteByte('(')
 		write(buf, e.x)
-		fmt.Fprintf(buf, " %c ", e.op)
+		fmt.Fprintf(buf, " %s ", opString(e.op))
+		write(buf, e.y)
+		buf.WriteByte(')')
+
+	case conditional:
+		buf.WriteByte('(')
+		write(buf, e.cond)
+		buf.WriteString(" ? ")
+		write(buf, e.x)
+		buf.WriteString(" : ")
 		write(buf, e.y)
 		buf.WriteByte(')')
 
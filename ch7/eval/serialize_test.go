@@ -0,0 +1,136 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+var roundTripTests = []string{
+	"sqrt(A / pi)",
+	"pow(x, 3) + pow(y, 3)",
+	"5 / 9 * (F - 32)",
+	"-1 + -x",
+	"x > 0 ? sqrt(x) : 0",
+	"!(x == 1) && x != 2 || y <= 3",
+	"x < 0 ? -1 : x == 0 ? 0 : 1",
+	"min(x, 3, y) + max(x) - 1.5e-3",
+	"exp(2i * pi) + 0.1",
+	"f()",
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, input := range roundTripTests {
+		expr, err := Parse(input)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		// infix
+		if e, err := Parse(Format(expr)); err != nil {
+			t.Errorf("Parse(Format(%s)): %v", input, err)
+		} else if !Equal(e, expr) {
+			t.Errorf("Parse(Format(%s)) = %s, not equal", input, Format(e))
+		}
+
+		// JSON
+		data, err := json.Marshal(expr)
+		if err != nil {
+			t.Errorf("json.Marshal(%s): %v", input, err)
+		} else if e, err := UnmarshalJSON(data); err != nil {
+			t.Errorf("UnmarshalJSON(%s): %v", data, err)
+		} else if !Equal(e, expr) {
+			t.Errorf("JSON round trip of %s = %s, not equal", input, Format(e))
+		}
+
+		// S-expression
+		s := FormatSExpr(expr)
+		if e, err := ParseSExpr(s); err != nil {
+			t.Errorf("ParseSExpr(%s): %v", s, err)
+		} else if !Equal(e, expr) {
+			t.Errorf("S-expression round trip of %s = %s, not equal", input, Format(e))
+		}
+	}
+}
+
+// TestRoundTripSynthetic checks trees that Parse cannot
+// produce, such as those with negative or non-finite literals.
+func TestRoundTripSynthetic(t *testing.T) {
+	for _, expr := range []Expr{
+		num(-1),
+		mul(num(math.Inf(-1)), Var("x")),
+		fn("sin", num(math.NaN())),
+		literal{val: -2, imag: true},
+	} {
+		data, err := json.Marshal(expr)
+		if err != nil {
+			t.Errorf("json.Marshal(%s): %v", Format(expr), err)
+		} else if e, err := UnmarshalJSON(data); err != nil || !Equal(e, expr) {
+			t.Errorf("JSON round trip of %s = %v, %v", Format(expr), e, err)
+		}
+		s := FormatSExpr(expr)
+		if e, err := ParseSExpr(s); err != nil || !Equal(e, expr) {
+			t.Errorf("S-expression round trip of %s = %v, %v", s, e, err)
+		}
+	}
+}
+
+func TestFormatSExpr(t *testing.T) {
+	for _, test := range []struct{ input, want string }{
+		{"x > 0 ? sin(x) : -1", "(? (> x 0) (sin x) (- 1))"},
+		{"a && b || !c", "(|| (&& a b) (! c))"},
+		{"pow(x, 2.5) / 2i", "(/ (pow x 2.5) 2i)"},
+	} {
+		expr, err := Parse(test.input)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got := FormatSExpr(expr); got != test.want {
+			t.Errorf("FormatSExpr(%s) = %s, want %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	expr, err := Parse("sin(x + 1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"kind":"call","fn":"sin","args":[{"kind":"binary","op":"+",` +
+		`"x":{"kind":"var","name":"x"},"y":{"kind":"literal","value":"1"}}]}`
+	if string(data) != want {
+		t.Errorf("json.Marshal = %s, want %s", data, want)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, test := range []struct{ input, want string }{
+		{`{"kind":"binary","op":"%","x":{"kind":"var","name":"x"}}`, `unknown binary op "%"`},
+		{`{"kind":"unary","op":"-"}`, "missing operand"},
+		{`{"kind":"lambda"}`, `unknown kind of expression "lambda"`},
+		{`{"kind":"literal","value":"one"}`, `strconv.ParseFloat: parsing "one": invalid syntax`},
+	} {
+		if _, err := UnmarshalJSON([]byte(test.input)); err == nil || err.Error() != test.want {
+			t.Errorf("UnmarshalJSON(%s) returned error %v, want %s", test.input, err, test.want)
+		}
+	}
+	for _, test := range []struct{ input, want string }{
+		{"(* x)", "1:2: bad operator * with 1 operands"},
+		{"(sin x", "1:7: got end of file, want ')'"},
+		{"()", "1:2: got ')', want operator or function"},
+		{"x y", "1:3: unexpected identifier y"},
+	} {
+		if _, err := ParseSExpr(test.input); err == nil || err.Error() != test.want {
+			t.Errorf("ParseSExpr(%s) returned error %v, want %s", test.input, err, test.want)
+		}
+	}
+}
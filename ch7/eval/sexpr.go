@@ -0,0 +1,197 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/scanner"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FormatSExpr formats an expression as an S-expression in prefix
+// notation, following the conventions of gopl.io/ch12/sexpr: a
+// variable is a symbol, a number is written in Go syntax, and every
+// other node is a list whose head is its operator or function name.
+// For example, x > 0 ? sin(x) : -1 is formatted as
+//
+//	(? (> x 0) (sin x) -1)
+//
+// A conditional has the head ?.  An imaginary literal is written with
+// an i suffix, e.g., 2i, and a non-finite literal as a quoted string,
+// e.g., "NaN".
+func FormatSExpr(e Expr) string {
+	var buf bytes.Buffer
+	writeSExpr(&buf, e)
+	return buf.String()
+}
+
+func writeSExpr(buf *bytes.Buffer, e Expr) {
+	switch e := e.(type) {
+	case literal:
+		if math.IsNaN(e.val) || math.IsInf(e.val, 0) {
+			fmt.Fprintf(buf, "%q", literalText(e))
+		} else {
+			buf.WriteString(literalText(e))
+		}
+		if e.imag {
+			buf.WriteByte('i')
+		}
+
+	case Var:
+		buf.WriteString(string(e))
+
+	case ident:
+		buf.WriteString(string(e.name))
+
+	case unary:
+		fmt.Fprintf(buf, "(%s ", opString(e.op))
+		writeSExpr(buf, e.x)
+		buf.WriteByte(')')
+
+	case binary:
+		fmt.Fprintf(buf, "(%s ", opString(e.op))
+		writeSExpr(buf, e.x)
+		buf.WriteByte(' ')
+		writeSExpr(buf, e.y)
+		buf.WriteByte(')')
+
+	case conditional:
+		buf.WriteString("(? ")
+		writeSExpr(buf, e.cond)
+		buf.WriteByte(' ')
+		writeSExpr(buf, e.x)
+		buf.WriteByte(' ')
+		writeSExpr(buf, e.y)
+		buf.WriteByte(')')
+
+	case call:
+		fmt.Fprintf(buf, "(%s", e.fn)
+		for _, arg := range e.args {
+			buf.WriteByte(' ')
+			writeSExpr(buf, arg)
+		}
+		buf.WriteByte(')')
+
+	default:
+		panic(fmt.Sprintf("unknown Expr: %T", e))
+	}
+}
+
+// ParseSExpr parses an expression in the form produced by FormatSExpr.
+func ParseSExpr(input string) (_ Expr, err error) {
+	lex := new(lexer)
+	defer func() {
+		switch x := recover().(type) {
+		case nil:
+			// no panic
+		case lexPanic:
+			err = &Error{lex.pos, string(x)}
+		default:
+			panic(x)
+		}
+	}()
+	lex.scan.Init(strings.NewReader(input))
+	lex.scan.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats | scanner.ScanStrings
+	lex.scan.Error = func(s *scanner.Scanner, msg string) {
+		lex.pos = s.Pos()
+		panic(lexPanic(msg))
+	}
+	lex.next() // initial lookahead
+	e := readSExpr(lex)
+	if lex.token != scanner.EOF {
+		return nil, &Error{lex.pos, fmt.Sprintf("unexpected %s", lex.describe())}
+	}
+	return e, nil
+}
+
+func readSExpr(lex *lexer) Expr {
+	switch lex.token {
+	case scanner.Ident:
+		v := Var(lex.text())
+		lex.next() // consume Ident
+		return v
+
+	case scanner.Int, scanner.Float, imaginary, scanner.String, '-':
+		return readNumber(lex)
+
+	case '(':
+		lex.next() // consume '('
+		pos := lex.pos
+		var head string
+		switch lex.token {
+		case scanner.Ident:
+			head = lex.text()
+		case scanner.EOF, ')':
+			panic(lexPanic(fmt.Sprintf("got %s, want operator or function", lex.describe())))
+		default:
+			head = opString(lex.token)
+		}
+		lex.next() // consume head
+		var args []Expr
+		for lex.token != ')' {
+			if lex.token == scanner.EOF {
+				panic(lexPanic("got end of file, want ')'"))
+			}
+			args = append(args, readSExpr(lex))
+		}
+		lex.next() // consume ')'
+		e, ok := makeSExpr(head, args, pos)
+		if !ok {
+			lex.pos = pos // report the error at the head
+			panic(lexPanic(fmt.Sprintf("bad operator %s with %d operands", head, len(args))))
+		}
+		return e
+	}
+	panic(lexPanic(fmt.Sprintf("unexpected %s", lex.describe())))
+}
+
+// readNumber reads a literal, which may be negative, imaginary,
+// or a quoted non-finite value.
+func readNumber(lex *lexer) Expr {
+	neg := ""
+	if lex.token == '-' {
+		neg = "-"
+		lex.next() // consume '-'
+	}
+	text := lex.text()
+	switch lex.token {
+	case scanner.String:
+		text, _ = strconv.Unquote(text)
+	case scanner.Int, scanner.Float, imaginary:
+	default:
+		panic(lexPanic(fmt.Sprintf("got %s, want number", lex.describe())))
+	}
+	lit, err := parseLiteral(neg+text, lex.token == imaginary)
+	if err != nil {
+		panic(lexPanic(err.Error()))
+	}
+	lex.next() // consume number
+	return lit
+}
+
+// makeSExpr returns the expression for a list whose head is head.
+// It reports false if head is not an operator with len(args) operands.
+func makeSExpr(head string, args []Expr, pos scanner.Position) (Expr, bool) {
+	r, _ := utf8.DecodeRuneInString(head)
+	switch {
+	case r == '_' || unicode.IsLetter(r): // a function name
+		return call{head, args, pos}, true
+	case head == "?" && len(args) == 3:
+		return conditional{args[0], args[1], args[2], pos}, true
+	case len(args) == 1:
+		if op, ok := unaryOp(head); ok {
+			return unary{op, args[0], pos}, true
+		}
+	case len(args) == 2:
+		if op, ok := binaryOp(head); ok {
+			return binary{op, args[0], args[1], pos}, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,145 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import "math"
+
+// Simplify returns an expression equivalent to e after constant
+// folding and the elimination of algebraic identities such as x*1,
+// x+0 and 0*x.  It assumes that all functions in Funcs are pure and
+// that the values of variables are finite, so that 0*x is 0.
+func Simplify(e Expr) Expr {
+	switch e := e.(type) {
+	case unary:
+		x := Simplify(e.x)
+		switch {
+		case e.op == '+':
+			return x
+		case e.op == '-' && isOp(x, '-'):
+			return x.(unary).x // -(-x) = x
+		}
+		return foldConstant(unary{e.op, x, e.pos})
+
+	case binary:
+		x, y := Simplify(e.x), Simplify(e.y)
+		switch e.op {
+		case '+':
+			switch {
+			case isLiteral(x, 0):
+				return y
+			case isLiteral(y, 0):
+				return x
+			case isOp(y, '-'):
+				return Simplify(binary{'-', x, y.(unary).x, e.pos}) // x + -y = x - y
+			}
+		case '-':
+			switch {
+			case isLiteral(y, 0):
+				return x
+			case isLiteral(x, 0):
+				return Simplify(neg(y))
+			case isOp(y, '-'):
+				return Simplify(binary{'+', x, y.(unary).x, e.pos}) // x - -y = x + y
+			}
+		case '*':
+			switch {
+			case isLiteral(x, 0), isLiteral(y, 0):
+				return num(0)
+			case isLiteral(x, 1):
+				return y
+			case isLiteral(y, 1):
+				return x
+			case isLiteral(x, -1):
+				return Simplify(neg(y))
+			case isLiteral(y, -1):
+				return Simplify(neg(x))
+			}
+		case '/':
+			if isLiteral(y, 1) {
+				return x
+			}
+		}
+		return foldConstant(binary{e.op, x, y, e.pos})
+
+	case conditional:
+		cond := Simplify(e.cond)
+		x, y := Simplify(e.x), Simplify(e.y)
+		if isConstant(cond) {
+			if cond.Eval(nil) != 0 {
+				return x
+			}
+			return y
+		}
+		return conditional{cond, x, y, e.pos}
+
+	case call:
+		args := make([]Expr, len(e.args))
+		for i, arg := range e.args {
+			args[i] = Simplify(arg)
+		}
+		c := call{e.fn, args, e.pos}
+		if f, ok := Funcs[c.fn]; !ok || !f.accepts(len(args)) {
+			return c // leave the error for Check to report
+		}
+		if c.fn == "pow" && isLiteral(args[1], 1) {
+			return args[0]
+		}
+		if c.fn == "pow" && isLiteral(args[1], 0) {
+			return num(1)
+		}
+		return foldConstant(c)
+	}
+	return e // literal or Var
+}
+
+// foldConstant replaces e by its value if e is a numeric expression
+// whose operands are all literals.  A boolean expression is never
+// replaced, since a literal would not be a valid boolean operand,
+// nor is one whose value is NaN or infinite, since no literal can
+// express it.
+func foldConstant(e Expr) Expr {
+	if kindOf(e) == numeric && isConstant(e) {
+		if v := e.Eval(nil); !math.IsNaN(v) && !math.IsInf(v, 0) {
+			return num(v)
+		}
+	}
+	return e
+}
+
+// isConstant reports whether e contains no variables.
+func isConstant(e Expr) bool {
+	switch e := e.(type) {
+	case literal:
+		return !e.imag
+	case unary:
+		return isConstant(e.x)
+	case binary:
+		return isConstant(e.x) && isConstant(e.y)
+	case conditional:
+		return isConstant(e.cond) && isConstant(e.x) && isConstant(e.y)
+	case call:
+		if f, ok := Funcs[e.fn]; !ok || !f.accepts(len(e.args)) {
+			return false
+		}
+		for _, arg := range e.args {
+			if !isConstant(arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isLiteral reports whether e is the literal k.
+func isLiteral(e Expr, k float64) bool {
+	l, ok := e.(literal)
+	return ok && !l.imag && l.val == k
+}
+
+// isOp reports whether e is a unary expression with operator op.
+func isOp(e Expr, op rune) bool {
+	u, ok := e.(unary)
+	return ok && u.op == op
+}
@@ -0,0 +1,162 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"fmt"
+	"text/scanner"
+)
+
+// The expression types other than Var are unexported, so that new
+// behavior is normally added as a method of Expr.  The functions in
+// this file let other packages inspect, traverse and build
+// expressions without access to those types.
+
+// Walk traverses e in depth-first order.  It calls f(e), and if that
+// returns true, it walks each subexpression of e in turn.
+func Walk(e Expr, f func(Expr) bool) {
+	if !f(e) {
+		return
+	}
+	for _, x := range children(e) {
+		Walk(x, f)
+	}
+}
+
+// Rewrite returns the expression obtained by replacing each
+// subexpression s of e, from the leaves up, by f(s).  The
+// subexpressions passed to f have already been rewritten.  To leave
+// s unchanged, f returns s itself.  Positions are preserved.
+func Rewrite(e Expr, f func(Expr) Expr) Expr {
+	switch e := e.(type) {
+	case unary:
+		e.x = Rewrite(e.x, f)
+		return f(e)
+	case binary:
+		e.x = Rewrite(e.x, f)
+		e.y = Rewrite(e.y, f)
+		return f(e)
+	case conditional:
+		e.cond = Rewrite(e.cond, f)
+		e.x = Rewrite(e.x, f)
+		e.y = Rewrite(e.y, f)
+		return f(e)
+	case call:
+		args := make([]Expr, len(e.args)) // don't modify the original
+		for i, arg := range e.args {
+			args[i] = Rewrite(arg, f)
+		}
+		e.args = args
+		return f(e)
+	}
+	return f(e)
+}
+
+// children returns the immediate subexpressions of e.
+func children(e Expr) []Expr {
+	switch e := e.(type) {
+	case unary:
+		return []Expr{e.x}
+	case binary:
+		return []Expr{e.x, e.y}
+	case conditional:
+		return []Expr{e.cond, e.x, e.y}
+	case call:
+		return e.args
+	}
+	return nil
+}
+
+// ---- accessors ----
+
+// Value reports the value of e, if it is a numeric constant.
+// An imaginary constant such as 2i has no real value.
+func Value(e Expr) (float64, bool) {
+	l, ok := e.(literal)
+	return l.val, ok && !l.imag
+}
+
+// Name reports the name of the variable, if e refers to one.
+func Name(e Expr) (Var, bool) {
+	switch e := e.(type) {
+	case Var:
+		return e, true
+	case ident:
+		return e.name, true
+	}
+	return "", false
+}
+
+// Operator reports the operator and operands, if e is an operator
+// expression.  A unary or binary operator is reported as in the
+// source text, e.g., "-" or "<=", and a conditional as "?:".
+func Operator(e Expr) (op string, operands []Expr, ok bool) {
+	switch e := e.(type) {
+	case unary:
+		return opString(e.op), children(e), true
+	case binary:
+		return opString(e.op), children(e), true
+	case conditional:
+		return "?:", children(e), true
+	}
+	return "", nil, false
+}
+
+// Function reports the function name and arguments, if e is a call.
+func Function(e Expr) (fn string, args []Expr, ok bool) {
+	c, ok := e.(call)
+	return c.fn, c.args, ok
+}
+
+// Pos returns the position in the source text of e, if known.
+// The position of an operator expression is that of its operator.
+func Pos(e Expr) scanner.Position {
+	switch e := e.(type) {
+	case literal:
+		return e.pos
+	case ident:
+		return e.pos
+	case unary:
+		return e.pos
+	case binary:
+		return e.pos
+	case conditional:
+		return e.pos
+	case call:
+		return e.pos
+	}
+	return scanner.Position{}
+}
+
+// ---- constructors ----
+
+// Literal returns a numeric constant.
+func Literal(x float64) Expr { return num(x) }
+
+// Unary returns the expression op x, where op is "+", "-" or "!".
+// It panics if op is not one of these.
+func Unary(op string, x Expr) Expr {
+	r, ok := unaryOp(op)
+	if !ok {
+		panic(fmt.Sprintf("invalid unary operator %q", op))
+	}
+	return unary{op: r, x: x}
+}
+
+// Binary returns the expression x op y, where op is an arithmetic,
+// comparison or logical operator, e.g., "*" or "&&".
+// It panics if op is not a binary operator.
+func Binary(op string, x, y Expr) Expr {
+	r, ok := binaryOp(op)
+	if !ok {
+		panic(fmt.Sprintf("invalid binary operator %q", op))
+	}
+	return binary{op: r, x: x, y: y}
+}
+
+// Conditional returns the expression cond ? x : y.
+func Conditional(cond, x, y Expr) Expr { return conditional{cond: cond, x: x, y: y} }
+
+// Call returns a call of the function fn, which should be a key of Funcs.
+func Call(fn string, args ...Expr) Expr { return call{fn: fn, args: args} }
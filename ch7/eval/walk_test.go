@@ -0,0 +1,106 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	expr, err := Parse("x > 0 ? pow(x, 2) + 1.5 : -(y * 3)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var consts []float64
+	var ops []string
+	Walk(expr, func(e Expr) bool {
+		if v, ok := Value(e); ok {
+			consts = append(consts, v)
+		}
+		if op, _, ok := Operator(e); ok {
+			ops = append(ops, op)
+		}
+		if fn, _, ok := Function(e); ok {
+			ops = append(ops, fn)
+			return false // skip the arguments of calls
+		}
+		return true
+	})
+	if got, want := fmt.Sprint(consts), "[0 1.5 3]"; got != want {
+		t.Errorf("constants = %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprint(ops), "[?: > + pow - *]"; got != want {
+		t.Errorf("operators = %s, want %s", got, want)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		f     func(Expr) Expr
+		want  string
+	}{
+		// Rename a variable.
+		{"x + sin(x * y)", func(e Expr) Expr {
+			if name, ok := Name(e); ok && name == "x" {
+				return Var("t")
+			}
+			return e
+		}, "(t + sin((t * y)))"},
+		// Substitute a subexpression.
+		{"sqrt(x) + 1", func(e Expr) Expr {
+			if _, ok := Name(e); ok {
+				return Binary("+", Var("a"), Var("b"))
+			}
+			return e
+		}, "(sqrt((a + b)) + 1)"},
+		// Double each constant; rewriting proceeds from the leaves up.
+		{"-(2 * x) < 3 ? 1 : 0", func(e Expr) Expr {
+			if v, ok := Value(e); ok {
+				return Literal(2 * v)
+			}
+			if op, args, ok := Operator(e); ok && op == "-" && len(args) == 1 {
+				return Call("abs", args[0])
+			}
+			return e
+		}, "((abs((4 * x)) < 6) ? 2 : 0)"},
+	} {
+		expr, err := Parse(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before := Format(expr)
+		if got := Format(Rewrite(expr, test.f)); got != test.want {
+			t.Errorf("Rewrite(%s) = %s, want %s", test.input, got, test.want)
+		}
+		if after := Format(expr); after != before {
+			t.Errorf("Rewrite modified its input: %s", after)
+		}
+	}
+}
+
+func TestConstructors(t *testing.T) {
+	e := Conditional(Binary("<=", Var("x"), Literal(1)), Unary("-", Var("x")), Call("sqrt", Var("x")))
+	if got, want := Format(e), "((x <= 1) ? (-x) : sqrt(x))"; got != want {
+		t.Errorf("Format = %s, want %s", got, want)
+	}
+	if err := e.Check(map[Var]bool{}); err != nil {
+		t.Error(err)
+	}
+	if p := Pos(e); p.IsValid() {
+		t.Errorf("Pos of constructed expression = %s, want none", p)
+	}
+	expr, _ := Parse("x\n + y")
+	if p := Pos(expr); p.Line != 2 || p.Column != 2 {
+		t.Errorf("Pos = %d:%d, want 2:2", p.Line, p.Column)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error(`Binary("%") did not panic`)
+		}
+	}()
+	Binary("%", Var("x"), Var("y"))
+}
@@ -0,0 +1,121 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// The mesh formats export the sampled height field itself, in the
+// units of x, y and z, rather than its projection onto the canvas.
+// Cells with a non-finite corner are omitted, leaving holes.
+
+// stl writes the surface f to w as a binary STL mesh of triangles,
+// two for each cell: an 80-byte header, the number of triangles, and
+// 50 bytes for each one.  This is the form that most 3-D printing
+// software expects.
+func (v *view) stl(w io.Writer, f func(x, y float64) float64) {
+	z, _, _ := v.grid(f)
+	var tris [][3][3]float64
+	v.eachTriangle(z, func(t [3][3]float64) { tris = append(tris, t) })
+
+	var header [80]byte // must not start with "solid"
+	copy(header[:], "surface")
+	w.Write(header[:]) // NOTE: ignoring errors
+	binary.Write(w, binary.LittleEndian, uint32(len(tris)))
+	for _, t := range tris {
+		var facet struct {
+			Normal, A, B, C [3]float32
+			Attributes      uint16
+		}
+		n := normal(t[0], t[1], t[2])
+		for i := 0; i < 3; i++ {
+			facet.Normal[i] = float32(n[i])
+			facet.A[i], facet.B[i], facet.C[i] = float32(t[0][i]), float32(t[1][i]), float32(t[2][i])
+		}
+		binary.Write(w, binary.LittleEndian, &facet)
+	}
+}
+
+// stlASCII writes the surface f to w as an ASCII STL mesh.
+func (v *view) stlASCII(w io.Writer, f func(x, y float64) float64) {
+	z, _, _ := v.grid(f)
+	fmt.Fprintln(w, "solid surface")
+	v.eachTriangle(z, func(t [3][3]float64) {
+		n := normal(t[0], t[1], t[2])
+		fmt.Fprintf(w, "facet normal %g %g %g\n outer loop\n", n[0], n[1], n[2])
+		for _, p := range t {
+			fmt.Fprintf(w, "  vertex %g %g %g\n", p[0], p[1], p[2])
+		}
+		fmt.Fprintf(w, " endloop\nendfacet\n")
+	})
+	fmt.Fprintln(w, "endsolid surface")
+}
+
+// eachTriangle calls visit for the two triangles of each cell that
+// eachFace visits, with their vertices in counterclockwise order.
+func (v *view) eachTriangle(z [][]float64, visit func(t [3][3]float64)) {
+	v.eachFace(z, func(a, b, c, d [3]float64) {
+		visit([3][3]float64{a, b, c})
+		visit([3][3]float64{a, c, d})
+	})
+}
+
+// obj writes the surface f to w as a Wavefront OBJ mesh with a
+// vertex for each finite corner and a quadrilateral face for each cell.
+func (v *view) obj(w io.Writer, f func(x, y float64) float64) {
+	z, _, _ := v.grid(f)
+	fmt.Fprintln(w, "o surface")
+	index := make(map[[2]int]int) // 1-based vertex number of corner (i, j)
+	for i := range z {
+		for j, h := range z[i] {
+			if finite(h) {
+				x, y := v.xy(i, j)
+				fmt.Fprintf(w, "v %g %g %g\n", x, y, h)
+				index[[2]int{i, j}] = len(index) + 1
+			}
+		}
+	}
+	for i := 0; i < v.cells; i++ {
+		for j := 0; j < v.cells; j++ {
+			a, b := index[[2]int{i, j}], index[[2]int{i + 1, j}]
+			c, d := index[[2]int{i + 1, j + 1}], index[[2]int{i, j + 1}]
+			if a > 0 && b > 0 && c > 0 && d > 0 {
+				fmt.Fprintf(w, "f %d %d %d %d\n", a, b, c, d)
+			}
+		}
+	}
+}
+
+// eachFace calls visit for each cell whose corners are all finite,
+// with its corners in counterclockwise order seen from above.
+func (v *view) eachFace(z [][]float64, visit func(a, b, c, d [3]float64)) {
+	corner := func(i, j int) [3]float64 {
+		x, y := v.xy(i, j)
+		return [3]float64{x, y, z[i][j]}
+	}
+	for i := 0; i < v.cells; i++ {
+		for j := 0; j < v.cells; j++ {
+			if finite(z[i][j]) && finite(z[i+1][j]) &&
+				finite(z[i+1][j+1]) && finite(z[i][j+1]) {
+				visit(corner(i, j), corner(i+1, j), corner(i+1, j+1), corner(i, j+1))
+			}
+		}
+	}
+}
+
+// normal returns the unit normal of the triangle abc, whose
+// vertices are in counterclockwise order.
+func normal(a, b, c [3]float64) [3]float64 {
+	u := [3]float64{b[0] - a[0], b[1] - a[1], b[2] - a[2]}
+	v := [3]float64{c[0] - a[0], c[1] - a[1], c[2] - a[2]}
+	n := [3]float64{u[1]*v[2] - u[2]*v[1], u[2]*v[0] - u[0]*v[2], u[0]*v[1] - u[1]*v[0]}
+	if l := math.Sqrt(n[0]*n[0] + n[1]*n[1] + n[2]*n[2]); l > 0 {
+		n[0], n[1], n[2] = n[0]/l, n[1]/l, n[2]/l
+	}
+	return n
+}
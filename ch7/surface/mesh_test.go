@@ -0,0 +1,126 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// tiny is a 2×2 grid, whose corners are at -15, 0 and 15.
+var tiny = view{cells: 2, xyrange: 30}
+
+func plane(x, y float64) float64 { return x + y }
+
+// holed is plane without the corner (-15, -15), which leaves a
+// hole in one of the four cells.
+func holed(x, y float64) float64 {
+	if x < -10 && y < -10 {
+		return math.NaN()
+	}
+	return plane(x, y)
+}
+
+func TestSTL(t *testing.T) {
+	for _, test := range []struct {
+		f    func(x, y float64) float64
+		tris int
+	}{
+		{plane, 8},
+		{holed, 6},
+	} {
+		var buf bytes.Buffer
+		tiny.stl(&buf, test.f)
+		b := buf.Bytes()
+		if want := 84 + 50*test.tris; len(b) != want {
+			t.Fatalf("binary STL is %d bytes, want %d", len(b), want)
+		}
+		if bytes.HasPrefix(b, []byte("solid")) {
+			t.Errorf("binary STL header starts with solid")
+		}
+		if n := binary.LittleEndian.Uint32(b[80:]); n != uint32(test.tris) {
+			t.Errorf("binary STL has %d triangles, want %d", n, test.tris)
+		}
+
+		buf.Reset()
+		tiny.stlASCII(&buf, test.f)
+		s := buf.String()
+		if !strings.HasPrefix(s, "solid surface\n") || !strings.HasSuffix(s, "endsolid surface\n") {
+			t.Errorf("ASCII STL lacks solid/endsolid:\n%s", s)
+		}
+		for _, word := range []string{"facet normal", "outer loop", "endloop", "endfacet"} {
+			if n := strings.Count(s, word); n != test.tris {
+				t.Errorf("ASCII STL has %d %q, want %d", n, word, test.tris)
+			}
+		}
+		if n := strings.Count(s, "vertex"); n != 3*test.tris {
+			t.Errorf("ASCII STL has %d vertices, want %d", n, 3*test.tris)
+		}
+	}
+}
+
+func TestSTLNormal(t *testing.T) {
+	// The normals of a flat surface point up, and each facet
+	// record holds the normal and three vertices as float32s.
+	var buf bytes.Buffer
+	tiny.stl(&buf, func(x, y float64) float64 { return 0 })
+	var facet [12]float32
+	if err := binary.Read(bytes.NewReader(buf.Bytes()[84:]), binary.LittleEndian, &facet); err != nil {
+		t.Fatal(err)
+	}
+	if n := facet[:3]; n[0] != 0 || n[1] != 0 || n[2] != 1 {
+		t.Errorf("normal = %v, want [0 0 1]", n)
+	}
+	if a := facet[3:6]; a[0] != -15 || a[1] != -15 {
+		t.Errorf("first vertex = %v, want [-15 -15 0]", a)
+	}
+}
+
+func TestOBJ(t *testing.T) {
+	for _, test := range []struct {
+		f               func(x, y float64) float64
+		vertices, faces int
+	}{
+		{plane, 9, 4},
+		{holed, 8, 3},
+	} {
+		var buf bytes.Buffer
+		tiny.obj(&buf, test.f)
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if lines[0] != "o surface" {
+			t.Errorf("first line = %q, want o surface", lines[0])
+		}
+		var v, f int
+		for _, line := range lines[1:] {
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "v":
+				v++
+				if len(fields) != 4 {
+					t.Errorf("bad vertex %q", line)
+				}
+			case "f":
+				f++
+				if len(fields) != 5 {
+					t.Errorf("bad face %q", line)
+				}
+				for _, index := range fields[1:] {
+					// The vertices precede the faces.
+					if n, err := strconv.Atoi(index); err != nil || n < 1 || n > v {
+						t.Errorf("face %q refers to a missing vertex", line)
+					}
+				}
+			default:
+				t.Errorf("unexpected line %q", line)
+			}
+		}
+		if v != test.vertices || f != test.faces {
+			t.Errorf("%d vertices and %d faces, want %d and %d", v, f, test.vertices, test.faces)
+		}
+	}
+}
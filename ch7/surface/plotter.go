@@ -0,0 +1,95 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+// A plotter draws simple shapes on a canvas, in pixel coordinates.
+// The top-down modes use it to produce either SVG or PNG.
+type plotter interface {
+	rect(x0, y0, x1, y1 float64, c color.RGBA)
+	line(x0, y0, x1, y1 float64, c color.RGBA)
+	text(x, y float64, s string, c color.RGBA) // y is the baseline
+}
+
+// An svgPlotter writes SVG elements to w.
+type svgPlotter struct{ w io.Writer }
+
+func (p svgPlotter) rect(x0, y0, x1, y1 float64, c color.RGBA) {
+	fmt.Fprintf(p.w, "<rect x='%g' y='%g' width='%g' height='%g' fill='%s'/>\n",
+		x0, y0, x1-x0, y1-y0, hex(c))
+}
+
+func (p svgPlotter) line(x0, y0, x1, y1 float64, c color.RGBA) {
+	fmt.Fprintf(p.w, "<line x1='%g' y1='%g' x2='%g' y2='%g' stroke='%s'/>\n",
+		x0, y0, x1, y1, hex(c))
+}
+
+func (p svgPlotter) text(x, y float64, s string, c color.RGBA) {
+	fmt.Fprintf(p.w, "<text x='%g' y='%g' fill='%s' font-size='10' "+
+		"font-family='sans-serif'>%s</text>\n", x, y, hex(c), s)
+}
+
+// A pngPlotter draws on an image.
+type pngPlotter struct{ img *image.RGBA }
+
+// rect fills the pixels whose centers lie within the rectangle,
+// so that adjacent rectangles tile exactly.
+func (p pngPlotter) rect(x0, y0, x1, y1 float64, c color.RGBA) {
+	r := image.Rect(
+		int(math.Ceil(x0-0.5)), int(math.Ceil(y0-0.5)),
+		int(math.Ceil(x1-0.5)), int(math.Ceil(y1-0.5)),
+	).Intersect(p.img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			p.img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+func (p pngPlotter) line(x0, y0, x1, y1 float64, c color.RGBA) {
+	drawLine(p.img, x0, y0, x1, y1, c)
+}
+
+// text draws s using a tiny built-in font that has only the
+// characters needed for numbers.  Other characters are skipped.
+func (p pngPlotter) text(x, y float64, s string, c color.RGBA) {
+	const scale = 2 // pixels per font dot
+	for _, r := range s {
+		glyph := font[r]
+		for row, bits := range glyph {
+			for col, bit := range bits {
+				if bit == '#' {
+					p.rect(x+float64(col*scale), y+float64((row-len(glyph))*scale),
+						x+float64((col+1)*scale), y+float64((row-len(glyph)+1)*scale), c)
+				}
+			}
+		}
+		x += 4 * scale
+	}
+}
+
+// font is a 3×5 dot font for numbers.
+var font = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", ".#.", ".#."},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'-': {"...", "...", "###", "...", "..."},
+	'+': {"...", ".#.", "###", ".#.", "..."},
+	'.': {"...", "...", "...", "...", ".#."},
+	'e': {"...", "##.", "###", "#..", ".##"},
+}
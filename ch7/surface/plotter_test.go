@@ -0,0 +1,70 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+var (
+	red  = color.RGBA{0xff, 0, 0, 0xff}
+	blue = color.RGBA{0, 0, 0xff, 0xff}
+)
+
+func TestSVGPlotter(t *testing.T) {
+	var buf bytes.Buffer
+	p := svgPlotter{&buf}
+	p.rect(1, 2, 4, 6, red)
+	p.line(0, 0, 3.5, 1, blue)
+	p.text(5, 10, "-1.5", red)
+	want := `<rect x='1' y='2' width='3' height='4' fill='#ff0000'/>
+<line x1='0' y1='0' x2='3.5' y2='1' stroke='#0000ff'/>
+<text x='5' y='10' fill='#ff0000' font-size='10' font-family='sans-serif'>-1.5</text>
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPNGPlotter(t *testing.T) {
+	// Adjacent rectangles tile the image exactly.
+	img := image.NewRGBA(image.Rect(0, 0, 5, 4))
+	p := pngPlotter{img}
+	p.rect(0, 0, 2.5, 4, red)
+	p.rect(2.5, 0, 5, 4, blue)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 5; x++ {
+			want := red
+			if x >= 2 {
+				want = blue
+			}
+			if got := img.RGBAAt(x, y); got != want {
+				t.Errorf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+
+	// The glyph for 1 has 8 dots of 2×2 pixels, above the baseline;
+	// unknown characters are skipped.
+	img = image.NewRGBA(image.Rect(0, 0, 20, 12))
+	p = pngPlotter{img}
+	p.text(0, 10, "x1", red)
+	n := 0
+	for y := 0; y < 12; y++ {
+		for x := 0; x < 20; x++ {
+			if img.RGBAAt(x, y) == red {
+				n++
+				if y >= 10 || x < 8 || x >= 14 {
+					t.Errorf("pixel (%d, %d) is outside the glyph", x, y)
+				}
+			}
+		}
+	}
+	if n != 8*4 {
+		t.Errorf("text set %d pixels, want %d", n, 8*4)
+	}
+}
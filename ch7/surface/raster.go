@@ -0,0 +1,82 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+)
+
+// png writes a PNG rendering of f to w.  Cells are painted from back
+// to front, so nearer cells hide those behind them.  Edges are drawn
+// only where cells are large enough for them not to obscure the fill.
+func (v *view) png(w io.Writer, f func(x, y float64) float64) {
+	z, zmin, zmax := v.grid(f)
+	img := image.NewRGBA(image.Rect(0, 0, v.width, v.height))
+	draw.Draw(img, img.Bounds(), image.White, image.ZP, draw.Src)
+	v.eachCell(z, func(p [4][2]float64, h float64) {
+		fillPolygon(img, p[:], v.color(h, zmin, zmax))
+		if math.Hypot(p[0][0]-p[2][0], p[0][1]-p[2][1]) >= 6 {
+			for k := range p {
+				q := p[(k+1)%len(p)]
+				drawLine(img, p[k][0], p[k][1], q[0], q[1], v.stroke)
+			}
+		}
+	})
+	png.Encode(w, img) // NOTE: ignoring errors
+}
+
+// fillPolygon fills the polygon with vertices p using the even-odd
+// rule, sampling each pixel at its center.
+func fillPolygon(img *image.RGBA, p [][2]float64, c color.RGBA) {
+	ymin, ymax := p[0][1], p[0][1]
+	for _, q := range p {
+		ymin = math.Min(ymin, q[1])
+		ymax = math.Max(ymax, q[1])
+	}
+	b := img.Bounds()
+	y0 := int(math.Max(math.Floor(ymin), float64(b.Min.Y)))
+	y1 := int(math.Min(math.Ceil(ymax), float64(b.Max.Y-1)))
+	var xs []float64
+	for y := y0; y <= y1; y++ {
+		sy := float64(y) + 0.5
+		// Find where the scan line crosses each edge.
+		xs = xs[:0]
+		for k, a := range p {
+			b := p[(k+1)%len(p)]
+			if (a[1] <= sy) != (b[1] <= sy) {
+				xs = append(xs, a[0]+(sy-a[1])/(b[1]-a[1])*(b[0]-a[0]))
+			}
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			x0 := int(math.Max(math.Ceil(xs[i]-0.5), float64(b.Min.X)))
+			x1 := int(math.Min(math.Floor(xs[i+1]-0.5), float64(b.Max.X-1)))
+			for x := x0; x <= x1; x++ {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// drawLine draws a one-pixel line from (x0,y0) to (x1,y1).
+func drawLine(img *image.RGBA, x0, y0, x1, y1 float64, c color.RGBA) {
+	n := int(math.Ceil(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))))
+	for k := 0; k <= n; k++ {
+		t := 0.0
+		if n > 0 {
+			t = float64(k) / float64(n)
+		}
+		x := int(math.Floor(x0 + t*(x1-x0)))
+		y := int(math.Floor(y0 + t*(y1-y0)))
+		if (image.Point{x, y}).In(img.Bounds()) {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFillPolygon(t *testing.T) {
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	for _, test := range []struct {
+		poly [][2]float64
+		want image.Rectangle // the pixels filled
+	}{
+		{[][2]float64{{1, 1}, {4, 1}, {4, 3}, {1, 3}}, image.Rect(1, 1, 4, 3)},
+		{[][2]float64{{1.2, 0.8}, {3.4, 0.8}, {3.4, 2.2}, {1.2, 2.2}}, image.Rect(1, 1, 3, 2)},
+		// Clipped to the image.
+		{[][2]float64{{-2, 4}, {8, 4}, {8, 9}, {-2, 9}}, image.Rect(0, 4, 6, 6)},
+		// Entirely outside it.
+		{[][2]float64{{7, 7}, {9, 7}, {9, 9}}, image.Rectangle{}},
+	} {
+		img := image.NewRGBA(image.Rect(0, 0, 6, 6))
+		fillPolygon(img, test.poly, red)
+		for y := 0; y < 6; y++ {
+			for x := 0; x < 6; x++ {
+				filled := img.RGBAAt(x, y) == red
+				if want := (image.Point{x, y}).In(test.want); filled != want {
+					t.Errorf("%v: pixel (%d, %d) filled = %t, want %t", test.poly, x, y, filled, want)
+				}
+			}
+		}
+	}
+}
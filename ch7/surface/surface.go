@@ -7,11 +7,16 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"image/color"
 	"io"
 	"log"
 	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
 //!+parseAndCheck
@@ -19,68 +24,241 @@ import "gopl.io/ch7/eval"
 
 //!-parseAndCheck
 
-// -- copied from gopl.io/ch3/surface --
+// -- adapted from gopl.io/ch3/surface --
 
-const (
-	width, height = 600, 320            // canvas size in pixels
-	cells         = 100                 // number of grid cells
-	xyrange       = 30.0                // x, y axis range (-xyrange..+xyrange)
-	xyscale       = width / 2 / xyrange // pixels per x or y unit
-	zscale        = height * 0.4        // pixels per z unit
-)
+// A view describes how a surface is rendered.
+type view struct {
+	width, height int     // canvas size in pixels
+	cells         int     // number of grid cells
+	xyrange       float64 // axis ranges (-xyrange..+xyrange)
+	angle         float64 // angle of x, y axes, in radians
+	levels        int     // number of contour lines
+
+	peak, valley, stroke color.RGBA // colors of highest and lowest cells, and edges
+}
 
-var sin30, cos30 = 0.5, math.Sqrt(3.0 / 4.0) // sin(30°), cos(30°)
+var defaultView = view{
+	width: 600, height: 320, cells: 100, xyrange: 30.0, angle: math.Pi / 6, levels: 10,
+	peak:   color.RGBA{0xff, 0x00, 0x00, 0xff},
+	valley: color.RGBA{0x00, 0x00, 0xff, 0xff},
+	stroke: color.RGBA{0x80, 0x80, 0x80, 0xff},
+}
 
-func corner(f func(x, y float64) float64, i, j int) (float64, float64) {
-	// find point (x,y) at corner of cell (i,j)
-	x := xyrange * (float64(i)/cells - 0.5)
-	y := xyrange * (float64(j)/cells - 0.5)
+// grid returns the height z at each corner (i, j) of the cells,
+// in z[i][j], and the range of the finite heights.
+func (v *view) grid(f func(x, y float64) float64) (z [][]float64, zmin, zmax float64) {
+	zmin, zmax = math.Inf(1), math.Inf(-1)
+	z = make([][]float64, v.cells+1)
+	for i := range z {
+		z[i] = make([]float64, v.cells+1)
+		for j := range z[i] {
+			x, y := v.xy(i, j)
+			h := f(x, y)
+			z[i][j] = h
+			if finite(h) {
+				zmin = math.Min(zmin, h)
+				zmax = math.Max(zmax, h)
+			}
+		}
+	}
+	return z, zmin, zmax
+}
 
-	z := f(x, y) // compute surface height z
+// xy returns the point (x,y) at corner (i,j) of the grid.
+func (v *view) xy(i, j int) (x, y float64) {
+	x = v.xyrange * (float64(i)/float64(v.cells) - 0.5)
+	y = v.xyrange * (float64(j)/float64(v.cells) - 0.5)
+	return x, y
+}
 
-	// project (x,y,z) isometrically onto 2-D SVG canvas (sx,sy)
-	sx := width/2 + (x-y)*cos30*xyscale
-	sy := height/2 + (x+y)*sin30*xyscale - z*zscale
+// project projects (x,y,z) isometrically onto the 2-D canvas (sx,sy).
+func (v *view) project(x, y, z float64) (sx, sy float64) {
+	xyscale := float64(v.width) / 2 / v.xyrange // pixels per x or y unit
+	zscale := float64(v.height) * 0.4           // pixels per z unit
+	sx = float64(v.width)/2 + (x-y)*math.Cos(v.angle)*xyscale
+	sy = float64(v.height)/2 + (x+y)*math.Sin(v.angle)*xyscale - z*zscale
 	return sx, sy
 }
 
-func surface(w io.Writer, f func(x, y float64) float64) {
-	fmt.Fprintf(w, "<svg xmlns='http://www.w3.org/2000/svg' "+
-		"style='stroke: grey; fill: white; stroke-width: 0.7' "+
-		"width='%d' height='%d'>", width, height)
-	for i := 0; i < cells; i++ {
-		for j := 0; j < cells; j++ {
-			ax, ay := corner(f, i+1, j)
-			bx, by := corner(f, i, j)
-			cx, cy := corner(f, i, j+1)
-			dx, dy := corner(f, i+1, j+1)
-			fmt.Fprintf(w, "<polygon points='%g,%g %g,%g %g,%g %g,%g'/>\n",
-				ax, ay, bx, by, cx, cy, dx, dy)
+// color returns the color of a cell of height z, blending from
+// valley at zmin to peak at zmax.
+func (v *view) color(z, zmin, zmax float64) color.RGBA {
+	t := 0.5
+	if zmax > zmin {
+		t = (z - zmin) / (zmax - zmin)
+	}
+	blend := func(a, b uint8) uint8 { return uint8(float64(a) + t*(float64(b)-float64(a)) + 0.5) }
+	return color.RGBA{
+		blend(v.valley.R, v.peak.R),
+		blend(v.valley.G, v.peak.G),
+		blend(v.valley.B, v.peak.B),
+		0xff,
+	}
+}
+
+// eachCell calls visit for each cell whose corners are all finite,
+// with the corners projected onto the canvas and the mean height of
+// the cell.  Cells are visited from back to front, so that nearer
+// cells drawn later hide those behind them.
+func (v *view) eachCell(z [][]float64, visit func(pts [4][2]float64, h float64)) {
+	for d := 0; d <= 2*(v.cells-1); d++ { // d = i+j grows towards the viewer
+	cell:
+		for i := 0; i < v.cells; i++ {
+			j := d - i
+			if j < 0 || j >= v.cells {
+				continue
+			}
+			corners := [4][2]int{{i + 1, j}, {i, j}, {i, j + 1}, {i + 1, j + 1}}
+			var pts [4][2]float64
+			var sum float64
+			for k, c := range corners {
+				h := z[c[0]][c[1]]
+				if !finite(h) {
+					continue cell // skip cells with non-finite corners
+				}
+				x, y := v.xy(c[0], c[1])
+				pts[k][0], pts[k][1] = v.project(x, y, h)
+				sum += h
+			}
+			visit(pts, sum/4)
 		}
 	}
+}
+
+func finite(x float64) bool { return !math.IsInf(x, 0) && !math.IsNaN(x) }
+
+// surface writes an SVG rendering of f to w.
+func (v *view) surface(w io.Writer, f func(x, y float64) float64) {
+	z, zmin, zmax := v.grid(f)
+	fmt.Fprintf(w, "<svg xmlns='http://www.w3.org/2000/svg' "+
+		"style='stroke: %s; stroke-width: 0.7' "+
+		"width='%d' height='%d'>", hex(v.stroke), v.width, v.height)
+	v.eachCell(z, func(p [4][2]float64, h float64) {
+		fmt.Fprintf(w, "<polygon points='%g,%g %g,%g %g,%g %g,%g' fill='%s'/>\n",
+			p[0][0], p[0][1], p[1][0], p[1][1], p[2][0], p[2][1], p[3][0], p[3][1],
+			hex(v.color(h, zmin, zmax)))
+	})
 	fmt.Fprintln(w, "</svg>")
 }
 
+// hex formats an opaque color as #rrggbb.
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// A format describes one of the ways to render a surface.
+type format struct {
+	contentType string
+	render      func(v *view, w io.Writer, f func(x, y float64) float64)
+}
+
+// modes maps each mode and format name to a format.
+var modes = map[string]map[string]format{
+	"surface": {
+		"svg":  {"image/svg+xml", (*view).surface},
+		"png":  {"image/png", (*view).png},
+		"stl":  {"model/stl", (*view).stl}, // binary
+		"stla": {"model/stl", (*view).stlASCII},
+		"obj":  {"model/obj", (*view).obj},
+	},
+	"heatmap": {
+		"svg": {"image/svg+xml", (*view).heatmapSVG},
+		"png": {"image/png", (*view).heatmapPNG},
+	},
+	"contour": {
+		"svg": {"image/svg+xml", (*view).contourSVG},
+		"png": {"image/png", (*view).contourPNG},
+	},
+}
+
+// parseView returns the default view modified by the query
+// parameters width, height, cells, range, angle (in degrees), levels,
+// peak, valley and stroke (colors such as ff0000 or #ff0000).
+func parseView(form url.Values) (view, error) {
+	v := defaultView
+	ints := []struct {
+		name     string
+		p        *int
+		min, max int
+	}{
+		{"width", &v.width, 10, 4000},
+		{"height", &v.height, 10, 4000},
+		{"cells", &v.cells, 1, 500},
+		{"levels", &v.levels, 1, 100},
+	}
+	for _, param := range ints {
+		if s := form.Get(param.name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < param.min || n > param.max {
+				return v, fmt.Errorf("bad %s %q: want integer in [%d, %d]",
+					param.name, s, param.min, param.max)
+			}
+			*param.p = n
+		}
+	}
+	if s := form.Get("range"); s != "" {
+		r, err := strconv.ParseFloat(s, 64)
+		if err != nil || !(r > 0 && r <= 1e6) {
+			return v, fmt.Errorf("bad range %q: want number in (0, 1e6]", s)
+		}
+		v.xyrange = r
+	}
+	if s := form.Get("angle"); s != "" {
+		deg, err := strconv.ParseFloat(s, 64)
+		if err != nil || !(deg >= 0 && deg <= 90) {
+			return v, fmt.Errorf("bad angle %q: want degrees in [0, 90]", s)
+		}
+		v.angle = deg * math.Pi / 180
+	}
+	colors := []struct {
+		name string
+		p    *color.RGBA
+	}{{"peak", &v.peak}, {"valley", &v.valley}, {"stroke", &v.stroke}}
+	for _, param := range colors {
+		if s := form.Get(param.name); s != "" {
+			c, err := parseColor(s)
+			if err != nil {
+				return v, fmt.Errorf("bad %s: %v", param.name, err)
+			}
+			*param.p = c
+		}
+	}
+	return v, nil
+}
+
+// parseColor parses a color in the form rrggbb or #rrggbb.
+func parseColor(s string) (color.RGBA, error) {
+	h := strings.TrimPrefix(s, "#")
+	n, err := strconv.ParseUint(h, 16, 32)
+	if err != nil || len(h) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, want rrggbb", s)
+	}
+	return color.RGBA{uint8(n >> 16), uint8(n >> 8), uint8(n), 0xff}, nil
+}
+
 // -- main code for gopl.io/ch7/surface --
 
+// Limits on untrusted expressions.
+var limits = eval.Limits{MaxLength: 1000, MaxDepth: 64, MaxNodes: 500}
+
+// evalBudget is the maximum number of evaluation steps per plot.
+// It allows a moderate expression on the finest grid, about 80
+// steps at each of its 501×501 corners.
+const evalBudget = 20000000
+
 //!+parseAndCheck
 func parseAndCheck(s string) (eval.Expr, error) {
 	if s == "" {
 		return nil, fmt.Errorf("empty expression")
 	}
-	expr, err := eval.Parse(s)
+	expr, err := eval.ParseLimited(s, limits)
 	if err != nil {
 		return nil, err
 	}
-	vars := make(map[eval.Var]bool)
-	if err := expr.Check(vars); err != nil {
+	allowed := map[eval.Var]bool{"x": true, "y": true, "r": true}
+	if err := eval.CheckAll(expr, allowed); err != nil {
 		return nil, err
 	}
-	for v := range vars {
-		if v != "x" && v != "y" && v != "r" {
-			return nil, fmt.Errorf("undefined variable: %s", v)
-		}
-	}
 	return expr, nil
 }
 
@@ -90,16 +268,73 @@ func parseAndCheck(s string) (eval.Expr, error) {
 func plot(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	// r.Form 是 url.Values 类型
-	expr, err := parseAndCheck(r.Form.Get("expr"))
+	v, err := parseView(r.Form)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mode, name := r.Form.Get("mode"), r.Form.Get("format")
+	if mode == "" {
+		mode = "surface"
+	}
+	if name == "" {
+		name = "svg"
+	}
+	formats, ok := modes[mode]
+	if !ok {
+		http.Error(w, fmt.Sprintf("bad mode %q: want surface, heatmap or contour", mode),
+			http.StatusBadRequest)
+		return
+	}
+	format, ok := formats[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("bad format %q for %s mode", name, mode),
+			http.StatusBadRequest)
+		return
+	}
+	input := r.Form.Get("expr")
+	expr, err := parseAndCheck(input)
+	if err != nil {
+		http.Error(w, "bad expr: "+eval.Explain(input, err), http.StatusBadRequest)
+		return
+	}
+	// Compiling the expression makes evaluation at each corner much cheaper.
+	prog, err := eval.Compile(expr, []eval.Var{"x", "y", "r"})
 	if err != nil {
 		http.Error(w, "bad expr: "+err.Error(), http.StatusBadRequest)
 		return
 	}
-	w.Header().Set("Content-Type", "image/svg+xml")
-	surface(w, func(x, y float64) float64 {
+	slots := make([]float64, 3)
+	// The meter stops evaluation if the expression is too costly
+	// or the client goes away; the first such error is saved in
+	// evalErr.  The image is buffered so that an error can still
+	// be reported with a suitable status.
+	meter := eval.NewMeter(r.Context(), evalBudget)
+	var evalErr error
+	var buf bytes.Buffer
+	format.render(&v, &buf, func(x, y float64) float64 {
+		if evalErr != nil {
+			return 0
+		}
 		r := math.Hypot(x, y) // distance from (0,0)
-		return expr.Eval(eval.Env{"x": x, "y": y, "r": r})
+		slots[0], slots[1], slots[2] = x, y, r
+		z, err := meter.EvalProgram(prog, slots)
+		evalErr = err
+		return z
 	})
+	if evalErr != nil {
+		if _, ok := evalErr.(*eval.LimitError); ok {
+			http.Error(w, "bad expr: "+evalErr.Error(), http.StatusBadRequest)
+		}
+		return // otherwise, the request was cancelled
+	}
+	w.Header().Set("Content-Type", format.contentType)
+	if strings.HasPrefix(format.contentType, "model/") {
+		// Meshes are for saving, not viewing.
+		ext := strings.TrimPrefix(format.contentType, "model/")
+		w.Header().Set("Content-Disposition", "attachment; filename=surface."+ext)
+	}
+	buf.WriteTo(w)
 }
 
 //!-plot
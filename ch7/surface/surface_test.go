@@ -0,0 +1,193 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// get returns the response of the plot handler to the query.
+func get(query url.Values) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	plot(w, httptest.NewRequest("GET", "/plot?"+query.Encode(), nil))
+	return w
+}
+
+func TestBudget(t *testing.T) {
+	// A moderate expression on the finest grid is within budget.
+	expr := "sin(x)*cos(y) + sin(x*y) + cos(x+y)*sin(r) + pow(x,2)/100 + sqrt(abs(x*y)) + exp(-r/10)"
+	query := url.Values{"expr": {expr}, "cells": {"500"}, "width": {"10"}, "height": {"10"}}
+	if w := get(query); w.Code != http.StatusOK {
+		t.Errorf("status %d: %s", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+
+	// One three times as large, though within the parse limits, is not.
+	query.Set("expr", expr+" + "+expr+" + "+expr)
+	w := get(query)
+	if want := "evaluation too costly"; w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), want) {
+		t.Errorf("status %d: %s; want 400 and %s", w.Code, strings.TrimSpace(w.Body.String()), want)
+	}
+
+	// On a coarser grid, it is within budget again.
+	query.Set("cells", "100")
+	if w := get(query); w.Code != http.StatusOK {
+		t.Errorf("cells=100: status %d: %s", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+}
+
+func TestParseView(t *testing.T) {
+	v, err := parseView(url.Values{
+		"width": {"200"}, "height": {"100"}, "cells": {"8"}, "range": {"2.5"},
+		"angle": {"90"}, "levels": {"3"}, "peak": {"#00ff00"}, "valley": {"102030"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := defaultView
+	want.width, want.height, want.cells, want.xyrange, want.levels = 200, 100, 8, 2.5, 3
+	want.angle = v.angle // checked below
+	want.peak = color.RGBA{0, 0xff, 0, 0xff}
+	want.valley = color.RGBA{0x10, 0x20, 0x30, 0xff}
+	if v != want {
+		t.Errorf("parseView = %+v, want %+v", v, want)
+	}
+	if v.angle < 1.5707 || v.angle > 1.5708 {
+		t.Errorf("angle = %g radians, want π/2", v.angle)
+	}
+
+	for _, bad := range []url.Values{
+		{"width": {"9"}},
+		{"height": {"4001"}},
+		{"cells": {"0"}},
+		{"cells": {"501"}},
+		{"cells": {"ten"}},
+		{"levels": {"101"}},
+		{"range": {"0"}},
+		{"range": {"NaN"}},
+		{"angle": {"-1"}},
+		{"angle": {"91"}},
+		{"peak": {"red"}},
+		{"stroke": {"#fff"}},
+	} {
+		if _, err := parseView(bad); err == nil {
+			t.Errorf("parseView(%s) succeeded, want error", bad.Encode())
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		want color.RGBA
+		ok   bool
+	}{
+		{"ff0000", color.RGBA{0xff, 0, 0, 0xff}, true},
+		{"#0080FF", color.RGBA{0, 0x80, 0xff, 0xff}, true},
+		{"", color.RGBA{}, false},
+		{"#fff", color.RGBA{}, false},
+		{"ff00001", color.RGBA{}, false},
+		{"gg0000", color.RGBA{}, false},
+		{"##ff0000", color.RGBA{}, false},
+		{"+f0000", color.RGBA{}, false},
+	} {
+		got, err := parseColor(test.s)
+		if got != test.want || (err == nil) != test.ok {
+			t.Errorf("parseColor(%q) = %v, %v", test.s, got, err)
+		}
+	}
+}
+
+func TestPlotErrors(t *testing.T) {
+	for _, query := range []url.Values{
+		{"expr": {"x"}, "cells": {"0"}},
+		{"expr": {"x"}, "mode": {"side"}},
+		{"expr": {"x"}, "format": {"gif"}},
+		{"expr": {"x"}, "mode": {"heatmap"}, "format": {"stl"}},
+		{"expr": {""}},
+		{"expr": {"x +"}},
+		{"expr": {"z"}},
+		{"expr": {"2i"}},
+	} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query.Encode(), w.Code)
+		}
+	}
+}
+
+// TestFormats checks the content type and basic structure of each
+// mode and format.
+func TestFormats(t *testing.T) {
+	isSVG := func(body []byte) bool {
+		dec := xml.NewDecoder(bytes.NewReader(body))
+		root := ""
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				return root == "svg"
+			}
+			if err != nil {
+				return false
+			}
+			if start, ok := tok.(xml.StartElement); ok && root == "" {
+				root = start.Name.Local
+			}
+		}
+	}
+	isPNG := func(body []byte) bool {
+		img, err := png.Decode(bytes.NewReader(body))
+		return err == nil && img.Bounds().Dx() == 120 && img.Bounds().Dy() == 100
+	}
+	isSTL := func(body []byte) bool { return len(body) == 84+50*2*4*4 }
+	isSTLA := func(body []byte) bool {
+		s := string(body)
+		return strings.HasPrefix(s, "solid surface\n") &&
+			strings.HasSuffix(s, "endsolid surface\n") &&
+			strings.Count(s, "facet normal") == 2*4*4
+	}
+	isOBJ := func(body []byte) bool {
+		s := string(body)
+		return strings.HasPrefix(s, "o surface\n") &&
+			strings.Count(s, "\nv ") == 5*5 && strings.Count(s, "\nf ") == 4*4
+	}
+	for _, test := range []struct {
+		mode, format string
+		contentType  string
+		ok           func([]byte) bool
+	}{
+		{"", "", "image/svg+xml", isSVG}, // the defaults
+		{"surface", "png", "image/png", isPNG},
+		{"surface", "stl", "model/stl", isSTL},
+		{"surface", "stla", "model/stl", isSTLA},
+		{"surface", "obj", "model/obj", isOBJ},
+		{"heatmap", "svg", "image/svg+xml", isSVG},
+		{"heatmap", "png", "image/png", isPNG},
+		{"contour", "svg", "image/svg+xml", isSVG},
+		{"contour", "png", "image/png", isPNG},
+	} {
+		w := get(url.Values{
+			"expr": {"x * y"}, "mode": {test.mode}, "format": {test.format},
+			"cells": {"4"}, "width": {"120"}, "height": {"100"},
+		})
+		name := test.mode + "/" + test.format
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status %d: %s", name, w.Code, w.Body)
+			continue
+		}
+		if ct := w.Header().Get("Content-Type"); ct != test.contentType {
+			t.Errorf("%s: Content-Type = %s, want %s", name, ct, test.contentType)
+		}
+		if !test.ok(w.Body.Bytes()) {
+			t.Errorf("%s: malformed output:\n%.300s", name, w.Body)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+)
+
+// The heatmap and contour modes view the grid from above, with x
+// increasing to the right and y increasing upwards.  A legend at
+// the right relates colors to heights.
+
+const legendWidth = 80 // pixels
+
+// A drawing draws a top-down view of the heights z on p.
+type drawing func(v *view, p plotter, z [][]float64, zmin, zmax float64)
+
+func (v *view) heatmapSVG(w io.Writer, f func(x, y float64) float64) {
+	v.topDownSVG(w, f, (*view).heatmap)
+}
+
+func (v *view) heatmapPNG(w io.Writer, f func(x, y float64) float64) {
+	v.topDownPNG(w, f, (*view).heatmap)
+}
+
+func (v *view) contourSVG(w io.Writer, f func(x, y float64) float64) {
+	v.topDownSVG(w, f, (*view).contour)
+}
+
+func (v *view) contourPNG(w io.Writer, f func(x, y float64) float64) {
+	v.topDownPNG(w, f, (*view).contour)
+}
+
+func (v *view) topDownSVG(w io.Writer, f func(x, y float64) float64, d drawing) {
+	z, zmin, zmax := v.grid(f)
+	fmt.Fprintf(w, "<svg xmlns='http://www.w3.org/2000/svg' "+
+		"width='%d' height='%d'>\n", v.width, v.height)
+	p := svgPlotter{w}
+	d(v, p, z, zmin, zmax)
+	v.legend(p, zmin, zmax)
+	fmt.Fprintln(w, "</svg>")
+}
+
+func (v *view) topDownPNG(w io.Writer, f func(x, y float64) float64, d drawing) {
+	z, zmin, zmax := v.grid(f)
+	img := image.NewRGBA(image.Rect(0, 0, v.width, v.height))
+	draw.Draw(img, img.Bounds(), image.White, image.ZP, draw.Src)
+	p := pngPlotter{img}
+	d(v, p, z, zmin, zmax)
+	v.legend(p, zmin, zmax)
+	png.Encode(w, img) // NOTE: ignoring errors
+}
+
+// plotArea returns the size of the part of the canvas left of the legend.
+func (v *view) plotArea() (pw, ph float64) {
+	pw = float64(v.width - legendWidth)
+	if pw < 1 {
+		pw = 1
+	}
+	return pw, float64(v.height)
+}
+
+// pixel returns the canvas position of grid coordinates (i, j),
+// which need not be integers.
+func (v *view) pixel(i, j float64) (px, py float64) {
+	pw, ph := v.plotArea()
+	n := float64(v.cells)
+	return i / n * pw, (1 - j/n) * ph
+}
+
+// heatmap fills each cell with the color of its mean height.
+func (v *view) heatmap(p plotter, z [][]float64, zmin, zmax float64) {
+	for i := 0; i < v.cells; i++ {
+		for j := 0; j < v.cells; j++ {
+			h := (z[i][j] + z[i+1][j] + z[i+1][j+1] + z[i][j+1]) / 4
+			if !finite(h) {
+				continue
+			}
+			x0, y0 := v.pixel(float64(i), float64(j+1))
+			x1, y1 := v.pixel(float64(i+1), float64(j))
+			p.rect(x0, y0, x1, y1, v.color(h, zmin, zmax))
+		}
+	}
+}
+
+// contour draws contour lines at v.levels evenly spaced heights
+// strictly between zmin and zmax, using marching squares, and labels
+// each line with its height.
+func (v *view) contour(p plotter, z [][]float64, zmin, zmax float64) {
+	if !(zmax > zmin) {
+		return // flat or empty
+	}
+	black := color.RGBA{0, 0, 0, 0xff}
+	for k := 1; k <= v.levels; k++ {
+		level := zmin + float64(k)*(zmax-zmin)/float64(v.levels+1)
+		c := v.color(level, zmin, zmax)
+		segs := marchingSquares(z, level)
+		for _, s := range segs {
+			x0, y0 := v.pixel(s[0], s[1])
+			x1, y1 := v.pixel(s[2], s[3])
+			p.line(x0, y0, x1, y1, c)
+		}
+		if len(segs) > 0 {
+			// Spread the labels across the plot, since the
+			// segments are in order of increasing x.
+			s := segs[len(segs)*k/(v.levels+1)]
+			x, y := v.pixel((s[0]+s[2])/2, (s[1]+s[3])/2)
+			p.text(x+2, y-2, fmt.Sprintf("%.3g", level), black)
+		}
+	}
+}
+
+// legend draws a color bar relating colors to heights.
+func (v *view) legend(p plotter, zmin, zmax float64) {
+	if !(zmax >= zmin) {
+		return // no finite heights
+	}
+	const steps, margin = 64, 10
+	x0 := float64(v.width-legendWidth) + margin
+	top, bottom := float64(margin), float64(v.height-margin)
+	for k := 0; k < steps; k++ {
+		t := float64(k) / steps // from the top down
+		y0 := top + t*(bottom-top)
+		y1 := top + (t+1.0/steps)*(bottom-top)
+		h := zmax - (t+0.5/steps)*(zmax-zmin)
+		p.rect(x0, y0, x0+15, y1, v.color(h, zmin, zmax))
+	}
+	black := color.RGBA{0, 0, 0, 0xff}
+	for k := 0; k <= 4; k++ {
+		t := float64(k) / 4
+		y := top + t*(bottom-top)
+		p.line(x0+15, y, x0+19, y, black)
+		p.text(x0+21, y+4, fmt.Sprintf("%.3g", zmax-t*(zmax-zmin)), black)
+	}
+}
+
+// marchingSquares returns the segments of the contour line z = level,
+// in grid coordinates, as {i0, j0, i1, j1}.  Cells with a non-finite
+// corner are skipped.
+func marchingSquares(z [][]float64, level float64) [][4]float64 {
+	var segs [][4]float64
+	for i := 0; i+1 < len(z); i++ {
+		for j := 0; j+1 < len(z[i]); j++ {
+			// Corners counterclockwise from bottom left.
+			corners := [4][2]int{{i, j}, {i + 1, j}, {i + 1, j + 1}, {i, j + 1}}
+			var h [4]float64
+			index := 0
+			for k, c := range corners {
+				h[k] = z[c[0]][c[1]]
+				if h[k] >= level {
+					index |= 1 << uint(k)
+				}
+			}
+			if !finite(h[0]) || !finite(h[1]) || !finite(h[2]) || !finite(h[3]) {
+				continue
+			}
+			// point returns where the contour crosses edge e,
+			// which joins corner e to corner e+1.
+			point := func(e int) (float64, float64) {
+				a, b := corners[e], corners[(e+1)%4]
+				t := (level - h[e]) / (h[(e+1)%4] - h[e])
+				return float64(a[0]) + t*float64(b[0]-a[0]),
+					float64(a[1]) + t*float64(b[1]-a[1])
+			}
+			edges := squareEdges[index]
+			if index == 5 || index == 10 {
+				// A saddle: use the center to decide which
+				// corners are connected.
+				center := (h[0] + h[1] + h[2] + h[3]) / 4
+				if (center >= level) == (index == 5) {
+					edges = []int{0, 1, 2, 3}
+				} else {
+					edges = []int{3, 0, 1, 2}
+				}
+			}
+			for e := 0; e < len(edges); e += 2 {
+				x0, y0 := point(edges[e])
+				x1, y1 := point(edges[e+1])
+				segs = append(segs, [4]float64{x0, y0, x1, y1})
+			}
+		}
+	}
+	return segs
+}
+
+// squareEdges gives, for each pattern of corners at or above the
+// level, the pairs of edges joined by contour segments.  Edge 0 is
+// the bottom, 1 the right, 2 the top, and 3 the left.
+var squareEdges = [16][]int{
+	1: {3, 0}, 2: {0, 1}, 3: {3, 1}, 4: {1, 2},
+	6: {0, 2}, 7: {3, 2}, 8: {2, 3}, 9: {0, 2},
+	11: {1, 2}, 12: {1, 3}, 13: {0, 1}, 14: {3, 0},
+}
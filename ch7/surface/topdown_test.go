@@ -0,0 +1,94 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// cell returns the grid of a single cell whose corners, counterclockwise
+// from the bottom left, have heights h.
+func cell(h [4]float64) [][]float64 {
+	return [][]float64{{h[0], h[3]}, {h[1], h[2]}}
+}
+
+// side returns the edge of the unit cell on which (x, y) lies:
+// 0 for the bottom, 1 the right, 2 the top, and 3 the left.
+func side(x, y float64) int {
+	switch {
+	case y == 0:
+		return 0
+	case x == 1:
+		return 1
+	case y == 1:
+		return 2
+	case x == 0:
+		return 3
+	}
+	return -1
+}
+
+func TestMarchingSquares(t *testing.T) {
+	// Each case sets the corners in index to hi and the others to lo,
+	// and gives the pairs of edges joined by the contour at 0.5.
+	const a, b = 1.0, 0.0     // center at the level
+	const hi, lo = 0.8, 0.0   // center below the level
+	const hi2, lo2 = 1.0, 0.2 // center above the level
+	for _, test := range []struct {
+		index  int
+		hi, lo float64
+		want   [][2]int
+	}{
+		{0, a, b, nil},
+		{1, a, b, [][2]int{{3, 0}}},
+		{2, a, b, [][2]int{{0, 1}}},
+		{3, a, b, [][2]int{{3, 1}}},
+		{4, a, b, [][2]int{{1, 2}}},
+		{5, a, b, [][2]int{{0, 1}, {2, 3}}}, // saddle: high corners joined
+		{6, a, b, [][2]int{{0, 2}}},
+		{7, a, b, [][2]int{{3, 2}}},
+		{8, a, b, [][2]int{{2, 3}}},
+		{9, a, b, [][2]int{{0, 2}}},
+		{10, a, b, [][2]int{{3, 0}, {1, 2}}}, // saddle: high corners joined
+		{11, a, b, [][2]int{{1, 2}}},
+		{12, a, b, [][2]int{{1, 3}}},
+		{13, a, b, [][2]int{{0, 1}}},
+		{14, a, b, [][2]int{{3, 0}}},
+		{15, a, b, nil},
+		{5, hi2, lo2, [][2]int{{0, 1}, {2, 3}}},
+		{5, hi, lo, [][2]int{{3, 0}, {1, 2}}}, // saddle: low corners joined
+		{10, hi2, lo2, [][2]int{{3, 0}, {1, 2}}},
+		{10, hi, lo, [][2]int{{0, 1}, {2, 3}}},
+	} {
+		var h [4]float64
+		for k := range h {
+			h[k] = test.lo
+			if test.index&(1<<uint(k)) != 0 {
+				h[k] = test.hi
+			}
+		}
+		var got [][2]int
+		for _, s := range marchingSquares(cell(h), 0.5) {
+			got = append(got, [2]int{side(s[0], s[1]), side(s[2], s[3])})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("case %d, corners %v: edges %v, want %v", test.index, h, got, test.want)
+		}
+	}
+}
+
+func TestMarchingSquaresInterpolation(t *testing.T) {
+	segs := marchingSquares(cell([4]float64{1, 0, 0, 0}), 0.25)
+	want := [][4]float64{{0, 0.75, 0.75, 0}}
+	if !reflect.DeepEqual(segs, want) {
+		t.Errorf("segments = %v, want %v", segs, want)
+	}
+
+	// A cell with a non-finite corner has no segments.
+	if segs := marchingSquares(cell([4]float64{1, 0, math.NaN(), 0}), 0.5); len(segs) != 0 {
+		t.Errorf("segments with NaN corner = %v, want none", segs)
+	}
+}